@@ -0,0 +1,201 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package admin provides an opt-in http.Handler for changing GOMAXPROCS on a live task,
+// such as during an incident. Register it on an admin mux with an Authorizer:
+//
+//	mux.Handle("/admin/gomaxecs", admin.Handler(authorize, maxprocs.WithLogger(log.Printf)))
+//
+// The handler accepts POST requests with an action form value, in the query or body:
+//
+//   - action=set&procs=N overrides GOMAXPROCS with N, which must be from 1 up to the ECS CPU
+//     limit, see maxprocs.Handle.Override.
+//   - action=revert reverts the override to the value resolved from the ECS metadata.
+//   - action=refresh resolves GOMAXPROCS again from the ECS metadata, reverting any override.
+//
+// It responds with the resulting maxprocs.Result as JSON, or with an Error as JSON. Every
+// request is written as an audit log line to the logger of the active maxprocs.Handle, and
+// to the logger configured for the handler, if any.
+package admin
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+// The actions accepted by the handler.
+const (
+	ActionSet     = "set"
+	ActionRevert  = "revert"
+	ActionRefresh = "refresh"
+)
+
+const (
+	contentTypeJSON = "application/json"
+	// refreshTimeout bounds a refresh, which is detached from the request so a client
+	// disconnecting does not cancel it once any override has been reverted.
+	refreshTimeout = 30 * time.Second
+)
+
+var (
+	errNoAuthorizer  = errors.New("no authorizer")
+	errNotSet        = errors.New("GOMAXPROCS has not been set")
+	errUnknownAction = errors.New("unknown action")
+	errInvalidProcs  = errors.New("procs must be an integer")
+)
+
+// Authorizer authorizes a request, returning the principal making it for the audit log,
+// or an error if the request is not authorized.
+type Authorizer func(r *http.Request) (principal string, err error)
+
+// Handler returns an http.Handler changing GOMAXPROCS, authorizing every request with
+// authorize. Requests are always denied if authorize is nil. The audit log lines are
+// written to the logger of the active Handle, and to the logger set by opts, e.g.
+// maxprocs.WithLogger or maxprocs.WithSlog, if any.
+func Handler(authorize Authorizer, opts ...config.Option) http.Handler {
+	return &handler{authorize: authorize, cfg: config.New(opts...)}
+}
+
+type handler struct {
+	authorize Authorizer
+	cfg       config.Config
+}
+
+// response is the JSON response of the handler.
+type response struct {
+	Result *maxprocs.Result `json:"Result,omitempty"`
+	Error  string           `json:"Error,omitempty"`
+}
+
+func (h *handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeJSON(w, http.StatusMethodNotAllowed, response{Error: "method not allowed"})
+
+		return
+	}
+
+	// The request is authorized before its body is parsed for the action.
+	principal, err := h.authorizeRequest(r)
+	if err != nil {
+		h.audit(slog.LevelWarn, "admin: Denied request",
+			[]slog.Attr{slog.String("remote_addr", r.RemoteAddr), slog.Any("error", err)},
+			"admin: Denied request from %s: %v", r.RemoteAddr, err)
+		writeJSON(w, http.StatusForbidden, response{Error: "forbidden"})
+
+		return
+	}
+
+	action := r.FormValue("action")
+
+	res, status, err := h.do(r, action)
+	if err != nil {
+		h.audit(slog.LevelWarn, "admin: Action failed",
+			[]slog.Attr{
+				slog.String("action", action), slog.String("principal", principal),
+				slog.String("remote_addr", r.RemoteAddr), slog.Any("error", err),
+			},
+			"admin: Action %q by %q from %s failed: %v", action, principal, r.RemoteAddr, err)
+		writeJSON(w, status, response{Error: err.Error()})
+
+		return
+	}
+
+	h.audit(slog.LevelWarn, "admin: Action succeeded",
+		[]slog.Attr{
+			slog.String("action", action), slog.String("principal", principal),
+			slog.String("remote_addr", r.RemoteAddr), slog.Int("procs", res.Procs), slog.String("source", string(res.Source)),
+		},
+		"admin: Action %q by %q from %s succeeded, GOMAXPROCS=%v (source %s)",
+		action, principal, r.RemoteAddr, res.Procs, res.Source)
+	writeJSON(w, http.StatusOK, response{Result: &res})
+}
+
+// audit writes an audit log line to the logger of the active Handle, if any, and to the
+// logger configured for the handler.
+func (h *handler) audit(level slog.Level, msg string, attrs []slog.Attr, format string, args ...any) {
+	cfgs := []config.Config{h.cfg}
+	if handle, ok := maxprocs.Active(); ok {
+		cfgs = append(cfgs, config.New(handle.Logger()))
+	}
+
+	for _, cfg := range cfgs {
+		cfg.Log(format, args...)
+		cfg.LogAttrs(level, msg, attrs...)
+	}
+}
+
+func (h *handler) authorizeRequest(r *http.Request) (string, error) {
+	if h.authorize == nil {
+		return "", errNoAuthorizer
+	}
+
+	return h.authorize(r)
+}
+
+// do performs the action on the active Handle, returning the result, or the error and
+// the status code to respond with.
+func (h *handler) do(r *http.Request, action string) (maxprocs.Result, int, error) {
+	handle, ok := maxprocs.Active()
+	if !ok {
+		return maxprocs.Result{}, http.StatusConflict, errNotSet
+	}
+
+	switch action {
+	case ActionSet:
+		procs, err := strconv.Atoi(r.FormValue("procs"))
+		if err != nil {
+			return maxprocs.Result{}, http.StatusBadRequest, errInvalidProcs
+		}
+
+		res, err := handle.Override(procs)
+		if errors.Is(err, maxprocs.ErrOutOfBounds) {
+			return res, http.StatusBadRequest, err
+		}
+
+		return res, http.StatusConflict, err //nolint:wrapcheck // error is already descriptive.
+	case ActionRevert:
+		res, err := handle.Revert()
+		return res, http.StatusConflict, err //nolint:wrapcheck // error is already descriptive.
+	case ActionRefresh:
+		ctx, cancel := context.WithTimeout(context.WithoutCancel(r.Context()), refreshTimeout)
+		defer cancel()
+
+		res, err := handle.RefreshContext(ctx)
+		return res, http.StatusInternalServerError, err //nolint:wrapcheck // error is already descriptive.
+	default:
+		return maxprocs.Result{}, http.StatusBadRequest, fmt.Errorf("%w %q", errUnknownAction, action)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, res response) {
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(res)
+}
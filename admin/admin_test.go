@@ -0,0 +1,273 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package admin_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rdforte/gomaxecs/admin"
+	"github.com/rdforte/gomaxecs/internal/maxprocstest"
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+type response struct {
+	Result *maxprocs.Result
+	Error  string
+}
+
+func TestAdmin_Handler(t *testing.T) {
+	maxprocstest.SetGOMAXPROCS(t)
+
+	tableTest := []struct {
+		name       string
+		form       url.Values
+		wantStatus int
+		wantProcs  int
+		wantSource maxprocs.Source
+		wantError  string
+		wantLog    string
+	}{
+		{
+			name:       "should override GOMAXPROCS",
+			form:       url.Values{"action": {"set"}, "procs": {"1"}},
+			wantStatus: http.StatusOK,
+			wantProcs:  1,
+			wantSource: maxprocs.SourceAdmin,
+			wantLog:    `admin: Action "set" by "alice" from 192.0.2.1:1234 succeeded, GOMAXPROCS=1 (source admin)`,
+		},
+		{
+			name:       "should revert override",
+			form:       url.Values{"action": {"revert"}},
+			wantStatus: http.StatusOK,
+			wantProcs:  2,
+			wantSource: maxprocs.SourceECS,
+			wantLog:    `admin: Action "revert" by "alice" from 192.0.2.1:1234 succeeded, GOMAXPROCS=2 (source ecs)`,
+		},
+		{
+			name:       "should refresh from metadata",
+			form:       url.Values{"action": {"refresh"}},
+			wantStatus: http.StatusOK,
+			wantProcs:  2,
+			wantSource: maxprocs.SourceECS,
+			wantLog:    `admin: Action "refresh" by "alice" from 192.0.2.1:1234 succeeded, GOMAXPROCS=2 (source ecs)`,
+		},
+		{
+			name:       "should reject override out of bounds",
+			form:       url.Values{"action": {"set"}, "procs": {"3"}},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "GOMAXPROCS override out of bounds: 3 is not from 1 to 2",
+			wantLog: `admin: Action "set" by "alice" from 192.0.2.1:1234 failed: ` +
+				`GOMAXPROCS override out of bounds: 3 is not from 1 to 2`,
+		},
+		{
+			name:       "should reject invalid procs",
+			form:       url.Values{"action": {"set"}, "procs": {"two"}},
+			wantStatus: http.StatusBadRequest,
+			wantError:  "procs must be an integer",
+		},
+		{
+			name:       "should reject unknown action",
+			form:       url.Values{"action": {"scale"}},
+			wantStatus: http.StatusBadRequest,
+			wantError:  `unknown action "scale"`,
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			logger := log.New(buf, "", 0)
+
+			rec := serve(admin.Handler(authorizeAlice, maxprocs.WithLogger(logger.Printf)), postForm(tt.form))
+			got := decode(t, rec)
+
+			assert.Equal(t, tt.wantStatus, rec.Code)
+			assert.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+			assert.Equal(t, tt.wantError, got.Error)
+			assert.Contains(t, buf.String(), tt.wantLog)
+
+			if tt.wantError != "" {
+				assert.Nil(t, got.Result)
+				return
+			}
+
+			require.NotNil(t, got.Result)
+			assert.Equal(t, tt.wantProcs, got.Result.Procs)
+			assert.Equal(t, tt.wantSource, got.Result.Source)
+			assert.Equal(t, tt.wantProcs, runtime.GOMAXPROCS(0))
+		})
+	}
+}
+
+func TestAdmin_Handler_DeniesUnauthorized(t *testing.T) {
+	maxprocstest.SetGOMAXPROCS(t)
+
+	tableTest := []struct {
+		name      string
+		authorize admin.Authorizer
+		wantLog   string
+	}{
+		{
+			name:      "should deny when authorizer fails",
+			authorize: func(*http.Request) (string, error) { return "", errors.New("bad token") },
+			wantLog:   "admin: Denied request from 192.0.2.1:1234: bad token",
+		},
+		{
+			name:    "should deny without authorizer",
+			wantLog: "admin: Denied request from 192.0.2.1:1234: no authorizer",
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			logger := log.New(buf, "", 0)
+
+			form := url.Values{"action": {"set"}, "procs": {"1"}}
+			rec := serve(admin.Handler(tt.authorize, maxprocs.WithLogger(logger.Printf)), postForm(form))
+
+			assert.Equal(t, http.StatusForbidden, rec.Code)
+			assert.Equal(t, "forbidden", decode(t, rec).Error)
+			assert.Contains(t, buf.String(), tt.wantLog)
+			assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+		})
+	}
+}
+
+func TestAdmin_Handler_AuditsToHandleLogger(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+
+	maxprocstest.SetGOMAXPROCS(t, maxprocs.WithLogger(logger.Printf))
+
+	rec := serve(admin.Handler(authorizeAlice), postForm(url.Values{"action": {"set"}, "procs": {"1"}}))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	rec = serve(admin.Handler(nil), postForm(url.Values{"action": {"revert"}}))
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+
+	assert.Contains(t, buf.String(),
+		`admin: Action "set" by "alice" from 192.0.2.1:1234 succeeded, GOMAXPROCS=1 (source admin)`)
+	assert.Contains(t, buf.String(), "admin: Denied request from 192.0.2.1:1234: no authorizer")
+}
+
+func TestAdmin_Handler_RefreshOutlivesRequest(t *testing.T) {
+	maxprocstest.SetGOMAXPROCS(t)
+
+	rec := serve(admin.Handler(authorizeAlice), postForm(url.Values{"action": {"set"}, "procs": {"1"}}))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rec = serve(admin.Handler(authorizeAlice), postForm(url.Values{"action": {"refresh"}}).WithContext(ctx))
+	got := decode(t, rec)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	require.NotNil(t, got.Result)
+	assert.Equal(t, maxprocs.SourceECS, got.Result.Source)
+	assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+}
+
+func TestAdmin_Handler_RejectsGet(t *testing.T) {
+	rec := serve(admin.Handler(authorizeAlice), httptest.NewRequest(http.MethodGet, "/admin?action=revert", nil))
+
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	assert.Equal(t, http.MethodPost, rec.Header().Get("Allow"))
+}
+
+func TestAdmin_Handler_ConflictsWhenNotSet(t *testing.T) {
+	rec := serve(admin.Handler(authorizeAlice), postForm(url.Values{"action": {"revert"}}))
+
+	assert.Equal(t, http.StatusConflict, rec.Code)
+	assert.Equal(t, "GOMAXPROCS has not been set", decode(t, rec).Error)
+}
+
+func TestAdmin_Handler_AuthorizesBeforeParsingForm(t *testing.T) {
+	maxprocstest.SetGOMAXPROCS(t)
+
+	authorize := func(r *http.Request) (string, error) {
+		assert.Nil(t, r.Form)
+		return "", errors.New("bad token")
+	}
+
+	rec := serve(admin.Handler(authorize), postForm(url.Values{"action": {"set"}, "procs": {"1"}}))
+
+	assert.Equal(t, http.StatusForbidden, rec.Code)
+	assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+}
+
+func TestAdmin_Handler_QuotesActionInAuditLog(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+
+	maxprocstest.SetGOMAXPROCS(t)
+
+	action := "scale\nadmin: Action set by \"bob\""
+	rec := serve(admin.Handler(authorizeAlice, maxprocs.WithLogger(logger.Printf)),
+		postForm(url.Values{"action": {action}}))
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+	assert.Contains(t, buf.String(), fmt.Sprintf("admin: Action %q by \"alice\" from 192.0.2.1:1234 failed", action))
+	assert.NotContains(t, buf.String(), "\nadmin: Action set by")
+}
+
+func authorizeAlice(*http.Request) (string, error) {
+	return "alice", nil
+}
+
+func postForm(form url.Values) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/admin", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.RemoteAddr = "192.0.2.1:1234"
+
+	return req
+}
+
+func serve(h http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	return rec
+}
+
+func decode(t *testing.T, rec *httptest.ResponseRecorder) response {
+	t.Helper()
+
+	var got response
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+
+	return got
+}
@@ -0,0 +1,78 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package async sets GOMAXPROCS based on ECS container and task CPU limits
+// without blocking program initialization.
+//
+// Importing the package resolves GOMAXPROCS from the ECS metadata in a goroutine
+// and applies it once ready. Until then, GOMAXPROCS is left unchanged.
+//
+//	import _ "github.com/rdforte/gomaxecs/async"
+//
+// Ready and Err report when GOMAXPROCS has been applied and whether it failed.
+// The same environment variables as package gomaxecs are supported.
+package async
+
+import (
+	"context"
+
+	"github.com/rdforte/gomaxecs/internal/startup"
+)
+
+// resolver resolves and applies GOMAXPROCS in a goroutine.
+type resolver struct {
+	ready chan struct{}
+	err   error
+}
+
+//nolint:gochecknoglobals // resolver started on import.
+var global *resolver
+
+func init() {
+	global = start(startup.Run)
+}
+
+func start(run func(ctx context.Context) (func(), error)) *resolver {
+	r := &resolver{ready: make(chan struct{})}
+
+	go func() {
+		defer close(r.ready)
+		_, r.err = run(context.Background())
+	}()
+
+	return r
+}
+
+// Ready returns a channel which is closed once GOMAXPROCS has been resolved
+// and applied, or resolving it failed.
+func Ready() <-chan struct{} {
+	return global.ready
+}
+
+// Err returns the error which occurred resolving GOMAXPROCS. Err returns nil
+// until Ready is closed.
+func Err() error {
+	select {
+	case <-global.ready:
+		return global.err
+	default:
+		return nil
+	}
+}
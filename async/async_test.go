@@ -0,0 +1,101 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// NOTE: This file is intentionally testing private functions.
+// This is to ensure that the goroutine started on import can be
+// tested against a test ECS agent.
+
+package async //nolint:testpackage // Test private function.
+
+import (
+	"context"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rdforte/gomaxecs/internal/startup"
+	"github.com/rdforte/gomaxecs/internal/task/tasktest"
+)
+
+func TestAsync_Ready_ClosedWhenECSEnvNotDetected(t *testing.T) {
+	select {
+	case <-Ready():
+	case <-time.After(time.Second):
+		t.Fatal("Ready not closed")
+	}
+
+	assert.NoError(t, Err())
+}
+
+func TestAsync_start_SetsGOMAXPROCSWithoutBlocking(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	wantCPUs := 2
+	containerCPU, taskCPU := wantCPUs<<10, wantCPUs
+
+	latency := 100 * time.Millisecond
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		WithLatency(latency).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	var undo func()
+
+	run := func(ctx context.Context) (func(), error) {
+		var err error
+		undo, err = startup.Run(ctx)
+
+		return undo, err
+	}
+
+	begin := time.Now()
+	r := start(run)
+
+	assert.Less(t, time.Since(begin), latency)
+	assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+
+	<-r.ready
+
+	defer undo()
+
+	require.NoError(t, r.err)
+	assert.Equal(t, wantCPUs, runtime.GOMAXPROCS(0))
+}
+
+func TestAsync_start_ReportsErrorWhenFailToSetGOMAXPROCS(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointInternalServerError().
+		WithTaskMetaEndpointInternalServerError().
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	r := start(startup.Run)
+
+	<-r.ready
+
+	assert.ErrorContains(t, r.err, "failed to set GOMAXPROCS")
+}
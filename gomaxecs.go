@@ -20,12 +20,28 @@
 
 // Package gomaxecs provides a simple way to set GOMAXPROCS based on ECS container
 // and task CPU limits.
+//
+// Importing the package sets GOMAXPROCS during program initialization, blocking
+// until the ECS metadata has been fetched. See package async to set GOMAXPROCS
+// without blocking.
+//
+// By default, logs are written using log.Printf. Setting the GOMAXECS_LOG_FORMAT
+// environment variable to "json" or "text" writes structured logs to stderr using
+// the log/slog JSON or text handler.
+//
+// Setting the GOMAXECS_TASK_TAGS environment variable to "true" reads gomaxecs
+// prefixed task tags as overrides, see maxprocs.WithTaskTags. Setting the
+// GOMAXECS_DRY_RUN environment variable to "true" logs the value GOMAXPROCS would
+// be set to without changing it, see maxprocs.WithDryRun. Setting the GOMAXECS_STRICT
+// environment variable to "true" panics during initialization if GOMAXPROCS could not
+// be resolved, see maxprocs.WithStrict. Setting the GOMAXECS_EMF environment variable
+// to "true" writes a CloudWatch EMF document to stdout, see maxprocs.WithEMF.
 package gomaxecs
 
 import (
-	"log"
+	"context"
 
-	"github.com/rdforte/gomaxecs/maxprocs"
+	"github.com/rdforte/gomaxecs/internal/startup"
 )
 
 func init() {
@@ -33,9 +49,5 @@ func init() {
 }
 
 func runSetMaxProcs() {
-	if maxprocs.IsECS() {
-		_, _ = maxprocs.Set(maxprocs.WithLogger(log.Printf))
-	} else {
-		log.Printf("gomaxecs: ECS environment not detected. Skipping set GOMAXPROCS")
-	}
+	_, _ = startup.Run(context.Background())
 }
@@ -25,10 +25,13 @@
 package gomaxecs //nolint:testpackage // Test private function.
 
 import (
+	"encoding/json"
+	"os"
 	"runtime"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/rdforte/gomaxecs/internal/task/tasktest"
 )
@@ -61,3 +64,26 @@ func TestGomaxecs_runSetMaxProcs_ECSEnvDetected(t *testing.T) {
 
 	assert.Equal(t, wantCPUs, runtime.GOMAXPROCS(0))
 }
+
+func TestGomaxecs_runSetMaxProcs_LogsJSONWhenLogFormatJSON(t *testing.T) {
+	t.Setenv("GOMAXECS_LOG_FORMAT", "json")
+
+	stderr, err := os.CreateTemp(t.TempDir(), "stderr")
+	require.NoError(t, err)
+
+	origStderr := os.Stderr
+	os.Stderr = stderr
+
+	defer func() { os.Stderr = origStderr }()
+
+	runSetMaxProcs()
+
+	out, err := os.ReadFile(stderr.Name())
+	require.NoError(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(out, &got))
+
+	assert.Equal(t, "INFO", got["level"])
+	assert.Equal(t, "gomaxecs: ECS environment not detected. Skipping set GOMAXPROCS", got["msg"])
+}
@@ -0,0 +1,175 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package gomaxecsvet defines an Analyzer reporting code which conflicts with gomaxecs
+// setting GOMAXPROCS:
+//
+//   - packages importing both gomaxecs and go.uber.org/automaxprocs, as both set
+//     GOMAXPROCS and whichever runs last wins.
+//   - calls to runtime.GOMAXPROCS changing GOMAXPROCS, which undo the value set by
+//     gomaxecs. Calls querying GOMAXPROCS, with a constant argument less than 1, are
+//     not reported.
+//   - blank imports of gomaxecs outside of package main, which set GOMAXPROCS for every
+//     program importing the package rather than leaving the decision to the program.
+//     Blank imports in test files are not reported.
+//
+// The Analyzer is run by the gomaxecsvet command, or by golangci-lint as a module plugin,
+// see package plugin.
+package gomaxecsvet
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"strconv"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	gomaxecsModule     = "github.com/rdforte/gomaxecs"
+	automaxprocsModule = "go.uber.org/automaxprocs"
+)
+
+// blankImports are the gomaxecs packages setting GOMAXPROCS when imported.
+//
+//nolint:gochecknoglobals // lookup table of import paths.
+var blankImports = map[string]bool{
+	gomaxecsModule:            true,
+	gomaxecsModule + "/async": true,
+}
+
+// Analyzer reports code which conflicts with gomaxecs setting GOMAXPROCS.
+//
+//nolint:gochecknoglobals // analyzers are declared as package vars by convention.
+var Analyzer = &analysis.Analyzer{
+	Name:     "gomaxecsvet",
+	Doc:      "report code conflicting with gomaxecs setting GOMAXPROCS",
+	URL:      "https://pkg.go.dev/github.com/rdforte/gomaxecs/gomaxecsvet",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	// gomaxecs itself sets GOMAXPROCS.
+	if inModule(pass.Pkg.Path(), gomaxecsModule) {
+		return nil, nil //nolint:nilnil // the analyzer has no result.
+	}
+
+	checkImports(pass)
+	checkSetters(pass)
+
+	return nil, nil //nolint:nilnil // the analyzer has no result.
+}
+
+// checkImports reports automaxprocs imports in packages also importing gomaxecs, and
+// blank imports of gomaxecs outside of package main.
+func checkImports(pass *analysis.Pass) {
+	var gomaxecs bool
+
+	var automaxprocs []*ast.ImportSpec
+
+	for _, file := range pass.Files {
+		test := strings.HasSuffix(pass.Fset.File(file.Pos()).Name(), "_test.go")
+
+		for _, spec := range file.Imports {
+			path, err := strconv.Unquote(spec.Path.Value)
+			if err != nil {
+				continue
+			}
+
+			if inModule(path, automaxprocsModule) {
+				automaxprocs = append(automaxprocs, spec)
+			}
+
+			if !inModule(path, gomaxecsModule) {
+				continue
+			}
+
+			gomaxecs = true
+
+			if blankImports[path] && spec.Name != nil && spec.Name.Name == "_" && pass.Pkg.Name() != "main" && !test {
+				pass.Reportf(spec.Pos(),
+					"blank import of %s sets GOMAXPROCS for every program importing package %s, import it in package main",
+					path, pass.Pkg.Name())
+			}
+		}
+	}
+
+	if !gomaxecs {
+		return
+	}
+
+	for _, spec := range automaxprocs {
+		pass.Reportf(spec.Pos(),
+			"import of %s conflicts with gomaxecs, both set GOMAXPROCS and whichever runs last wins",
+			spec.Path.Value)
+	}
+}
+
+// checkSetters reports calls to runtime.GOMAXPROCS changing GOMAXPROCS.
+func checkSetters(pass *analysis.Pass) {
+	insp, ok := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	if !ok {
+		return
+	}
+
+	insp.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(node ast.Node) {
+		call, ok := node.(*ast.CallExpr)
+		if !ok || len(call.Args) != 1 || !isGOMAXPROCS(typeutil.Callee(pass.TypesInfo, call)) {
+			return
+		}
+
+		if query(pass.TypesInfo, call.Args[0]) {
+			return
+		}
+
+		pass.Reportf(call.Pos(),
+			"runtime.GOMAXPROCS changes GOMAXPROCS, undoing the value set by gomaxecs, "+
+				"use maxprocs.Handle.Override to change it at runtime")
+	})
+}
+
+func isGOMAXPROCS(obj types.Object) bool {
+	fn, ok := obj.(*types.Func)
+	return ok && fn.Pkg() != nil && fn.Pkg().Path() == "runtime" && fn.Name() == "GOMAXPROCS"
+}
+
+// query returns true if arg is a constant less than 1, with which runtime.GOMAXPROCS
+// returns GOMAXPROCS without changing it.
+func query(info *types.Info, arg ast.Expr) bool {
+	tv, ok := info.Types[arg]
+	if !ok || tv.Value == nil {
+		return false
+	}
+
+	n, exact := constant.Int64Val(constant.ToInt(tv.Value))
+
+	return exact && n < 1
+}
+
+// inModule returns true if the package path is the module or a package within it.
+func inModule(path, module string) bool {
+	return path == module || strings.HasPrefix(path, module+"/")
+}
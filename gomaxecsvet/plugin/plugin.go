@@ -0,0 +1,67 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package plugin registers the gomaxecsvet analyzer as a golangci-lint module plugin.
+// Add it to .custom-gcl.yml:
+//
+//	version: v1.60.0
+//	plugins:
+//	  - module: github.com/rdforte/gomaxecs/gomaxecsvet
+//	    import: github.com/rdforte/gomaxecs/gomaxecsvet/plugin
+//	    version: latest
+//
+// Then build the custom golangci-lint with golangci-lint custom and enable the linter
+// in .golangci.yml:
+//
+//	linters-settings:
+//	  custom:
+//	    gomaxecsvet:
+//	      type: module
+//	      description: Reports code conflicting with gomaxecs setting GOMAXPROCS.
+//	linters:
+//	  enable:
+//	    - gomaxecsvet
+package plugin
+
+import (
+	"github.com/golangci/plugin-module-register/register"
+	"golang.org/x/tools/go/analysis"
+
+	"github.com/rdforte/gomaxecs/gomaxecsvet"
+)
+
+func init() {
+	register.Plugin(gomaxecsvet.Analyzer.Name, New)
+}
+
+// New returns the gomaxecsvet linter plugin. The analyzer has no settings.
+func New(any) (register.LinterPlugin, error) {
+	return linter{}, nil
+}
+
+type linter struct{}
+
+func (linter) BuildAnalyzers() ([]*analysis.Analyzer, error) {
+	return []*analysis.Analyzer{gomaxecsvet.Analyzer}, nil
+}
+
+func (linter) GetLoadMode() string {
+	return register.LoadModeTypesInfo
+}
@@ -0,0 +1,6 @@
+package blank
+
+import (
+	_ "github.com/rdforte/gomaxecs"       // want `blank import of github.com/rdforte/gomaxecs sets GOMAXPROCS for every program importing package blank`
+	_ "github.com/rdforte/gomaxecs/async" // want `blank import of github.com/rdforte/gomaxecs/async sets GOMAXPROCS`
+)
@@ -0,0 +1,9 @@
+package blank
+
+import (
+	"testing"
+
+	_ "github.com/rdforte/gomaxecs"
+)
+
+func TestBlank(t *testing.T) {}
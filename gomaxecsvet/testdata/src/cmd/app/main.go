@@ -0,0 +1,11 @@
+package main
+
+import (
+	"runtime"
+
+	_ "github.com/rdforte/gomaxecs"
+)
+
+func main() {
+	println(runtime.GOMAXPROCS(0))
+}
@@ -0,0 +1,11 @@
+package conflict
+
+import (
+	_ "go.uber.org/automaxprocs" // want `import of "go.uber.org/automaxprocs" conflicts with gomaxecs`
+
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+func Set() (func(), error) {
+	return maxprocs.Set()
+}
@@ -0,0 +1,7 @@
+package conflict
+
+import automaxprocs "go.uber.org/automaxprocs/maxprocs" // want `import of "go.uber.org/automaxprocs/maxprocs" conflicts with gomaxecs`
+
+func SetAutomaxprocs() (func(), error) {
+	return automaxprocs.Set()
+}
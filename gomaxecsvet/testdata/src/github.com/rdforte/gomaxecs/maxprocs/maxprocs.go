@@ -0,0 +1,9 @@
+package maxprocs
+
+import "runtime"
+
+func Set() (func(), error) {
+	runtime.GOMAXPROCS(2)
+
+	return func() {}, nil
+}
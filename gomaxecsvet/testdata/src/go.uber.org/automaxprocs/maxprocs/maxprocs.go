@@ -0,0 +1,5 @@
+package maxprocs
+
+func Set() (func(), error) {
+	return func() {}, nil
+}
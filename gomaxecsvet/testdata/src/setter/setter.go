@@ -0,0 +1,14 @@
+package setter
+
+import "runtime"
+
+const query = 0
+
+func Set(n int) int {
+	runtime.GOMAXPROCS(4) // want `runtime.GOMAXPROCS changes GOMAXPROCS, undoing the value set by gomaxecs`
+	runtime.GOMAXPROCS(n) // want `runtime.GOMAXPROCS changes GOMAXPROCS`
+
+	runtime.GOMAXPROCS(-1)
+
+	return runtime.GOMAXPROCS(query) + runtime.GOMAXPROCS(0)
+}
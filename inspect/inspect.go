@@ -0,0 +1,178 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package inspect provides an http.Handler showing how gomaxecs set GOMAXPROCS, for
+// checking a live task without exec-ing into it. Register it on an admin mux, in the
+// same way as net/http/pprof:
+//
+//	mux.Handle("/debug/gomaxecs", inspect.Handler())
+//
+// The handler renders the result and decision trace of the last attempt to set GOMAXPROCS,
+// the options in effect, the raw container and task metadata used, and the current
+// GOMAXPROCS and Go memory limit. It responds with HTML, or with JSON when requested
+// with the format=json query parameter or an Accept header of application/json.
+//
+// The metadata includes the container labels and task tags, so as with net/http/pprof
+// the handler should not be exposed publicly.
+package inspect
+
+import (
+	"bytes"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"strings"
+
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+const (
+	contentTypeJSON = "application/json"
+	contentTypeHTML = "text/html; charset=utf-8"
+	formatJSON      = "json"
+)
+
+// Handler returns an http.Handler serving the inspection of GOMAXPROCS.
+func Handler() http.Handler {
+	return http.HandlerFunc(serveHTTP)
+}
+
+func serveHTTP(w http.ResponseWriter, r *http.Request) {
+	in := maxprocs.Inspect()
+
+	if wantsJSON(r) {
+		w.Header().Set("Content-Type", contentTypeJSON)
+
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(in)
+
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypeHTML)
+	_ = page.Execute(w, in)
+}
+
+// wantsJSON returns true if the request asks for JSON rather than HTML.
+func wantsJSON(r *http.Request) bool {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format == formatJSON
+	}
+
+	return strings.Contains(r.Header.Get("Accept"), contentTypeJSON)
+}
+
+// indent indents the raw metadata for display, returning it as is if it can not be indented.
+func indent(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, raw, "", "  "); err != nil {
+		return string(raw)
+	}
+
+	return buf.String()
+}
+
+//nolint:gochecknoglobals // template is parsed once and safe for concurrent use.
+var page = template.Must(template.New("inspect").Funcs(template.FuncMap{"indent": indent}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>gomaxecs</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 1em; }
+th, td { border: 1px solid #ccc; padding: 0.25em 0.5em; text-align: left; vertical-align: top; }
+pre { background: #f6f6f6; padding: 1em; overflow: auto; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>gomaxecs</h1>
+
+<h2>Runtime</h2>
+<table>
+<tr><th>GOMAXPROCS</th><td>{{.GOMAXPROCS}}</td></tr>
+<tr><th>Memory limit</th><td>{{.MemoryLimit}}</td></tr>
+<tr><th>NumCPU</th><td>{{.NumCPU}}</td></tr>
+<tr><th>Active</th><td>{{.Active}}</td></tr>
+</table>
+
+<h2>Result</h2>
+{{with .Error}}<p class="error">{{.}}</p>{{end}}
+{{with .Result}}
+<table>
+<tr><th>Procs</th><td>{{.Procs}}</td></tr>
+<tr><th>Previous</th><td>{{.Previous}}</td></tr>
+<tr><th>Source</th><td>{{.Source}}</td></tr>
+<tr><th>Set by</th><td>{{.SetBy}}</td></tr>
+<tr><th>Container CPU</th><td>{{.ContainerCPU}}</td></tr>
+<tr><th>Task CPU</th><td>{{.TaskCPU}}</td></tr>
+<tr><th>Container memory</th><td>{{.ContainerMemory}}</td></tr>
+<tr><th>Task memory</th><td>{{.TaskMemory}}</td></tr>
+<tr><th>Memory limit</th><td>{{.MemoryLimit}}</td></tr>
+<tr><th>Previous memory limit</th><td>{{.PreviousMemoryLimit}}</td></tr>
+<tr><th>Dry run</th><td>{{.DryRun}}</td></tr>
+<tr><th>Duration</th><td>{{.Duration}}</td></tr>
+<tr><th>Cluster</th><td>{{.Identity.Cluster}}</td></tr>
+<tr><th>Task ARN</th><td>{{.Identity.TaskARN}}</td></tr>
+<tr><th>Task definition</th><td>{{.Identity.Family}}{{with .Identity.Revision}}:{{.}}{{end}}</td></tr>
+<tr><th>Container</th><td>{{.Identity.ContainerName}}</td></tr>
+</table>
+{{end}}
+
+<h2>Trace</h2>
+<table>
+<tr><th>Time</th><th>Level</th><th>Message</th></tr>
+{{range .Trace}}<tr><td>{{.Time.Format "15:04:05.000"}}</td><td>{{.Level}}</td><td>{{.Message}}</td></tr>
+{{end}}</table>
+
+<h2>Options</h2>
+{{with .Options}}
+<table>
+<tr><th>Container metadata URI</th><td>{{.ContainerMetadataURI}}</td></tr>
+<tr><th>Task metadata URI</th><td>{{.TaskMetadataURI}}</td></tr>
+<tr><th>Task tags</th><td>{{.TaskTags}}</td></tr>
+<tr><th>Runtime policy</th><td>{{.RuntimePolicy}}</td></tr>
+<tr><th>Env policy</th><td>{{.EnvPolicy}}</td></tr>
+<tr><th>Max retries</th><td>{{.MaxRetries}}</td></tr>
+<tr><th>Retry backoff</th><td>{{.RetryBackoff}}</td></tr>
+<tr><th>Watchdog interval</th><td>{{.WatchdogInterval}}</td></tr>
+<tr><th>Watchdog reapply</th><td>{{.WatchdogReapply}}</td></tr>
+<tr><th>HTTP timeout</th><td>{{.HTTPTimeout}}</td></tr>
+<tr><th>Max response size</th><td>{{.MaxResponseSize}}</td></tr>
+<tr><th>Procs</th><td>{{.Procs}}</td></tr>
+<tr><th>Headroom</th><td>{{.Headroom}}</td></tr>
+<tr><th>Memory limit ratio</th><td>{{.MemoryLimitRatio}}</td></tr>
+<tr><th>Disable</th><td>{{.Disable}}</td></tr>
+<tr><th>Dry run</th><td>{{.DryRun}}</td></tr>
+<tr><th>Strict</th><td>{{.Strict}}</td></tr>
+<tr><th>EMF</th><td>{{.EMF}}</td></tr>
+</table>
+{{end}}
+
+<h2>Container metadata</h2>
+<pre>{{with .ContainerMetadata}}{{indent .}}{{else}}Not fetched{{end}}</pre>
+
+<h2>Task metadata</h2>
+<pre>{{with .TaskMetadata}}{{indent .}}{{else}}Not fetched{{end}}</pre>
+</body>
+</html>
+`))
@@ -0,0 +1,119 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package inspect_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rdforte/gomaxecs/inspect"
+	"github.com/rdforte/gomaxecs/internal/maxprocstest"
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+func TestInspect_Handler(t *testing.T) {
+	maxprocstest.SetGOMAXPROCS(t)
+
+	tableTest := []struct {
+		name            string
+		target          string
+		accept          string
+		wantContentType string
+	}{
+		{
+			name:            "should serve HTML by default",
+			target:          "/debug/gomaxecs",
+			wantContentType: "text/html; charset=utf-8",
+		},
+		{
+			name:            "should serve JSON when requested with format",
+			target:          "/debug/gomaxecs?format=json",
+			wantContentType: "application/json",
+		},
+		{
+			name:            "should serve JSON when accepted",
+			target:          "/debug/gomaxecs",
+			accept:          "application/json",
+			wantContentType: "application/json",
+		},
+		{
+			name:            "should serve HTML when format takes precedence over accept",
+			target:          "/debug/gomaxecs?format=html",
+			accept:          "application/json",
+			wantContentType: "text/html; charset=utf-8",
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.target, nil)
+			req.Header.Set("Accept", tt.accept)
+
+			rec := httptest.NewRecorder()
+			inspect.Handler().ServeHTTP(rec, req)
+
+			assert.Equal(t, http.StatusOK, rec.Code)
+			assert.Equal(t, tt.wantContentType, rec.Header().Get("Content-Type"))
+		})
+	}
+}
+
+func TestInspect_Handler_ServesJSON(t *testing.T) {
+	maxprocstest.SetGOMAXPROCS(t)
+
+	rec := httptest.NewRecorder()
+	inspect.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/gomaxecs?format=json", nil))
+
+	var got maxprocs.Inspection
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &got))
+
+	want := maxprocs.Inspect()
+	assert.Equal(t, want.Result.Procs, got.Result.Procs)
+	assert.Equal(t, want.Result.Identity, got.Result.Identity)
+	assert.Equal(t, want.Options, got.Options)
+	assert.Equal(t, len(want.Trace), len(got.Trace))
+	assert.JSONEq(t, string(want.TaskMetadata), string(got.TaskMetadata))
+	assert.Equal(t, want.GOMAXPROCS, got.GOMAXPROCS)
+}
+
+func TestInspect_Handler_ServesHTML(t *testing.T) {
+	maxprocstest.SetGOMAXPROCS(t)
+
+	rec := httptest.NewRecorder()
+	inspect.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/debug/gomaxecs", nil))
+
+	body := rec.Body.String()
+	for _, want := range []string{
+		"<tr><th>Source</th><td>ecs</td></tr>",
+		"<tr><th>Task definition</th><td>service:3</td></tr>",
+		"<tr><th>Container</th><td>&lt;app&gt;</td></tr>",
+		"maxprocs: Updated GOMAXPROCS=2",
+		"<tr><th>Env policy</th><td>honor</td></tr>",
+		"&#34;Family&#34;: &#34;service&#34;",
+	} {
+		assert.Contains(t, body, want)
+	}
+}
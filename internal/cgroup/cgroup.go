@@ -0,0 +1,198 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package cgroup provides functionality for reading the CPU quota of the
+// current process's cgroup.
+package cgroup
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	procCgroupPath = "proc/self/cgroup"
+	cgroupRoot     = "sys/fs/cgroup"
+	cpuMaxFile     = "cpu.max"
+	cfsQuotaFile   = "cpu.cfs_quota_us"
+	cfsPeriodFile  = "cpu.cfs_period_us"
+	cpuController  = "cpu"
+	noLimitV2      = "max"
+	defaultPeriod  = 100000 // default CFS period in microseconds.
+)
+
+// CPUQuota returns the CPU quota, in number of CPUs, of the cgroup the current
+// process belongs to and a boolean indicating if a quota is set.
+// Both cgroup v2 (cpu.max) and cgroup v1 (cpu.cfs_quota_us) are supported.
+// fsys is expected to be rooted at "/".
+func CPUQuota(fsys fs.FS) (float64, bool, error) {
+	v2Path, v1Path, err := cgroupPaths(fsys)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if quota, ok, err := cpuQuotaV2(fsys, v2Path); !errors.Is(err, fs.ErrNotExist) {
+		return quota, ok, err
+	}
+
+	if quota, ok, err := cpuQuotaV1(fsys, v1Path); !errors.Is(err, fs.ErrNotExist) {
+		return quota, ok, err
+	}
+
+	return 0, false, nil
+}
+
+// cgroupPaths returns the cgroup v2 and cgroup v1 cpu controller paths of the
+// current process as listed in /proc/self/cgroup.
+func cgroupPaths(fsys fs.FS) (string, string, error) {
+	data, err := fs.ReadFile(fsys, procCgroupPath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return "/", "/", nil
+	}
+
+	if err != nil {
+		return "", "", fmt.Errorf("failed to read %s: %w", procCgroupPath, err)
+	}
+
+	v2Path, v1Path := "/", "/"
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		// Each line is of the form hierarchy-ID:controller-list:cgroup-path.
+		fields := strings.SplitN(scanner.Text(), ":", 3) //nolint:mnd // 3 fields per line.
+		if len(fields) != 3 {                            //nolint:mnd // 3 fields per line.
+			continue
+		}
+
+		if fields[0] == "0" && fields[1] == "" {
+			v2Path = fields[2]
+			continue
+		}
+
+		for _, controller := range strings.Split(fields[1], ",") {
+			if controller == cpuController {
+				v1Path = fields[2]
+			}
+		}
+	}
+
+	return v2Path, v1Path, nil
+}
+
+func cpuQuotaV2(fsys fs.FS, cgroupPath string) (float64, bool, error) {
+	data, err := readFirst(fsys,
+		path.Join(cgroupRoot, cgroupPath, cpuMaxFile),
+		path.Join(cgroupRoot, cpuMaxFile),
+	)
+	if err != nil {
+		return 0, false, err
+	}
+
+	// cpu.max is of the form "$MAX $PERIOD" where $MAX may be "max".
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 || fields[0] == noLimitV2 {
+		return 0, false, nil
+	}
+
+	quota, err := strconv.ParseFloat(fields[0], 64)
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to parse %s: %w", cpuMaxFile, err)
+	}
+
+	period := float64(defaultPeriod)
+	if len(fields) > 1 {
+		period, err = strconv.ParseFloat(fields[1], 64)
+		if err != nil {
+			return 0, false, fmt.Errorf("failed to parse %s: %w", cpuMaxFile, err)
+		}
+	}
+
+	return quotaToCPU(quota, period)
+}
+
+func cpuQuotaV1(fsys fs.FS, cgroupPath string) (float64, bool, error) {
+	quota, err := readFloat(fsys, cfsQuotaFile,
+		path.Join(cgroupRoot, cpuController, cgroupPath, cfsQuotaFile),
+		path.Join(cgroupRoot, cpuController, cfsQuotaFile),
+	)
+	if err != nil {
+		return 0, false, err
+	}
+
+	if quota < 0 {
+		return 0, false, nil
+	}
+
+	period, err := readFloat(fsys, cfsPeriodFile,
+		path.Join(cgroupRoot, cpuController, cgroupPath, cfsPeriodFile),
+		path.Join(cgroupRoot, cpuController, cfsPeriodFile),
+	)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return quotaToCPU(quota, period)
+}
+
+func quotaToCPU(quota, period float64) (float64, bool, error) {
+	if quota <= 0 || period <= 0 {
+		return 0, false, nil
+	}
+
+	return quota / period, true, nil
+}
+
+func readFloat(fsys fs.FS, name string, paths ...string) (float64, error) {
+	data, err := readFirst(fsys, paths...)
+	if err != nil {
+		return 0, err
+	}
+
+	v, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+
+	return v, nil
+}
+
+// readFirst reads the first of paths that exists.
+func readFirst(fsys fs.FS, paths ...string) ([]byte, error) {
+	for _, p := range paths {
+		data, err := fs.ReadFile(fsys, p)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", p, err)
+		}
+
+		return data, nil
+	}
+
+	return nil, fs.ErrNotExist
+}
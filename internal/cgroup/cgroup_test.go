@@ -0,0 +1,114 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package cgroup_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rdforte/gomaxecs/internal/cgroup"
+)
+
+func TestCgroup_CPUQuota(t *testing.T) {
+	t.Parallel()
+
+	tableTest := []struct {
+		name      string
+		fsys      fstest.MapFS
+		wantQuota float64
+		wantOK    bool
+	}{
+		{
+			name: "should get quota from cgroup v2 cpu.max",
+			fsys: fstest.MapFS{
+				"proc/self/cgroup":      {Data: []byte("0::/\n")},
+				"sys/fs/cgroup/cpu.max": {Data: []byte("200000 100000\n")},
+			},
+			wantQuota: 2,
+			wantOK:    true,
+		},
+		{
+			name: "should get quota from nested cgroup v2 path",
+			fsys: fstest.MapFS{
+				"proc/self/cgroup":               {Data: []byte("0::/ecs/task\n")},
+				"sys/fs/cgroup/ecs/task/cpu.max": {Data: []byte("50000 100000\n")},
+				"sys/fs/cgroup/cpu.max":          {Data: []byte("max 100000\n")},
+			},
+			wantQuota: 0.5,
+			wantOK:    true,
+		},
+		{
+			name: "should get no quota when cgroup v2 cpu.max is max",
+			fsys: fstest.MapFS{
+				"proc/self/cgroup":      {Data: []byte("0::/\n")},
+				"sys/fs/cgroup/cpu.max": {Data: []byte("max 100000\n")},
+			},
+		},
+		{
+			name: "should get quota from cgroup v1 cpu.cfs_quota_us",
+			fsys: fstest.MapFS{
+				"proc/self/cgroup":                    {Data: []byte("2:cpuacct:/\n1:cpu:/\n0::/\n")},
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":  {Data: []byte("400000\n")},
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us": {Data: []byte("100000\n")},
+			},
+			wantQuota: 4,
+			wantOK:    true,
+		},
+		{
+			name: "should get no quota when cgroup v1 cpu.cfs_quota_us is -1",
+			fsys: fstest.MapFS{
+				"proc/self/cgroup":                    {Data: []byte("1:cpu,cpuacct:/ecs/task\n")},
+				"sys/fs/cgroup/cpu/cpu.cfs_quota_us":  {Data: []byte("-1\n")},
+				"sys/fs/cgroup/cpu/cpu.cfs_period_us": {Data: []byte("100000\n")},
+			},
+		},
+		{
+			name: "should get no quota when no cgroup files exist",
+			fsys: fstest.MapFS{},
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			quota, ok, err := cgroup.CPUQuota(tt.fsys)
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantOK, ok)
+			assert.InDelta(t, tt.wantQuota, quota, 0)
+		})
+	}
+}
+
+func TestCgroup_CPUQuota_ReturnsErrorWhenQuotaInvalid(t *testing.T) {
+	t.Parallel()
+
+	fsys := fstest.MapFS{
+		"proc/self/cgroup":      {Data: []byte("0::/\n")},
+		"sys/fs/cgroup/cpu.max": {Data: []byte("invalid 100000\n")},
+	}
+
+	_, _, err := cgroup.CPUQuota(fsys)
+	assert.ErrorContains(t, err, "failed to parse cpu.max")
+}
@@ -19,65 +19,103 @@
 // THE SOFTWARE.
 
 // Package client provides an HTTP client.
+//
+// By default the client is built on net/http. Building with the gomaxecs_lite
+// build tag instead uses a minimal HTTP/1.1 GET implementation on top of
+// net.Dialer, avoiding linking net/http and crypto/tls into the binary.
 package client
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"net"
-	"net/http"
 
 	"github.com/rdforte/gomaxecs/internal/config"
 )
 
-// New returns a new Client.
-func New(cfg config.Client) *Client {
-	return &Client{
-		client: &http.Client{
-			Timeout: cfg.HTTPTimeout,
-			Transport: &http.Transport{
-				DialContext: (&net.Dialer{
-					Timeout: cfg.DialTimeout,
-				}).DialContext,
-				MaxIdleConns:          cfg.MaxIdleConns,
-				MaxIdleConnsPerHost:   cfg.MaxIdleConnsPerHost,
-				DisableKeepAlives:     cfg.DisableKeepAlives,
-				IdleConnTimeout:       cfg.IdleConnTimeout,
-				TLSHandshakeTimeout:   cfg.TLSHandshakeTimeout,
-				ResponseHeaderTimeout: cfg.ResponseHeaderTimeout,
-			},
-		},
+// ErrResponseTooLarge is returned when reading a response body larger than the max response size.
+var ErrResponseTooLarge = errors.New("response too large")
+
+// HTTP status codes, defined here so the lite client does not depend on net/http.
+const (
+	StatusOK                  = 200
+	StatusTooManyRequests     = 429
+	StatusInternalServerError = 500
+)
+
+// Response represents an HTTP response.
+type Response struct {
+	StatusCode int
+	Body       []byte
+}
+
+// Get performs an HTTP GET request, reading the whole response body.
+func (c *Client) Get(ctx context.Context, url string) (*Response, error) {
+	var res *Response
+
+	err := c.Stream(ctx, url, func(status int, body io.Reader) error {
+		b, err := io.ReadAll(body)
+		if err != nil {
+			return fmt.Errorf("failed to read response body: %w", err)
+		}
+
+		res = &Response{status, b}
+
+		return nil
+	})
+
+	return res, err
+}
+
+func maxResponseSize(cfg config.Client) int64 {
+	if cfg.MaxResponseSize <= 0 {
+		return config.DefaultMaxResponseSize
 	}
+
+	return cfg.MaxResponseSize
 }
 
-// Client is an HTTP client.
-type Client struct {
-	client *http.Client
+func newResponseTooLargeError(maxSize int64) error {
+	return fmt.Errorf("%w: exceeds max size of %d bytes", ErrResponseTooLarge, maxSize)
 }
 
-// Get performs an HTTP GET request.
-func (c *Client) Get(ctx context.Context, url string) (*Response, error) {
-	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+// maxBytesReader fails with ErrResponseTooLarge once more than max bytes have been read.
+type maxBytesReader struct {
+	r         io.Reader
+	max       int64
+	remaining int64
+}
+
+func newMaxBytesReader(r io.Reader, maxSize int64) *maxBytesReader {
+	return &maxBytesReader{r, maxSize, maxSize}
+}
+
+func (r *maxBytesReader) Read(p []byte) (int, error) {
+	if r.remaining < 0 {
+		return 0, newResponseTooLargeError(r.max)
 	}
 
-	res, err := c.client.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("failed to perform HTTP GET request: %w", err)
+	// Read one byte past the limit to detect a body exceeding it.
+	if int64(len(p)) > r.remaining+1 {
+		p = p[:r.remaining+1]
 	}
-	defer res.Body.Close()
 
-	body, err := io.ReadAll(res.Body)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+	n, err := r.r.Read(p)
+	r.remaining -= int64(n)
+
+	if r.remaining < 0 {
+		return n - 1, newResponseTooLargeError(r.max)
 	}
 
-	return &Response{res.StatusCode, body}, nil
+	return n, err //nolint:wrapcheck // return underlying error.
 }
 
-type Response struct {
-	StatusCode int
-	Body       []byte
+// errReader always fails with err.
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
 }
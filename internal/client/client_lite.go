@@ -0,0 +1,367 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+//go:build gomaxecs_lite
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+)
+
+const (
+	schemeHTTP      = "http"
+	defaultPort     = "80"
+	maxHeaderBytes  = 16 << 10
+	maxChunkLineLen = 64
+)
+
+var (
+	errUnsupportedScheme = errors.New("unsupported protocol scheme")
+	errMalformedResponse = errors.New("malformed HTTP response")
+	errHeaderTooLarge    = errors.New("response header too large")
+)
+
+// New returns a new Client.
+func New(cfg config.Client) *Client {
+	return &Client{
+		dialer:          &net.Dialer{Timeout: cfg.DialTimeout},
+		timeout:         cfg.HTTPTimeout,
+		maxResponseSize: maxResponseSize(cfg),
+	}
+}
+
+// Client is a minimal HTTP/1.1 client, supporting only plain HTTP GET requests.
+type Client struct {
+	dialer          *net.Dialer
+	timeout         time.Duration
+	maxResponseSize int64
+}
+
+// Stream performs an HTTP GET request, calling fn with the response status code and body.
+// Reading more than the max response size from the body fails with ErrResponseTooLarge.
+// The error returned by fn is returned as is.
+func (c *Client) Stream(ctx context.Context, rawURL string, fn func(status int, body io.Reader) error) error {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	if u.Scheme != schemeHTTP {
+		return fmt.Errorf("failed to perform HTTP GET request: %w %q", errUnsupportedScheme, u.Scheme)
+	}
+
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	conn, err := c.dial(ctx, u)
+	if err != nil {
+		return fmt.Errorf("failed to perform HTTP GET request: %w", err)
+	}
+	defer conn.Close()
+
+	status, contentLength, body, err := roundTrip(conn, u)
+	if err != nil {
+		return fmt.Errorf("failed to perform HTTP GET request: %w", withContextErr(ctx, err))
+	}
+
+	if contentLength > c.maxResponseSize {
+		return fn(status, errReader{newResponseTooLargeError(c.maxResponseSize)})
+	}
+
+	return fn(status, &contextReader{ctx, newMaxBytesReader(body, c.maxResponseSize)})
+}
+
+// contextReader returns the context error alongside read errors caused by ctx being done.
+type contextReader struct {
+	ctx context.Context //nolint:containedctx // used to describe read errors.
+	r   io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return n, withContextErr(r.ctx, err)
+	}
+
+	return n, err //nolint:wrapcheck // return underlying error.
+}
+
+// dial connects to the host of u. The connection is closed as soon as ctx is done.
+func (c *Client) dial(ctx context.Context, u *url.URL) (net.Conn, error) {
+	host := u.Host
+	if u.Port() == "" {
+		host = net.JoinHostPort(u.Hostname(), defaultPort)
+	}
+
+	conn, err := c.dialer.DialContext(ctx, "tcp", host)
+	if err != nil {
+		return nil, err //nolint:wrapcheck // wrapped by caller.
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+
+	stop := context.AfterFunc(ctx, func() { _ = conn.SetDeadline(time.Unix(1, 0)) })
+
+	return &stopConn{conn, stop}, nil
+}
+
+// stopConn stops the cancellation of the connection when closed.
+type stopConn struct {
+	net.Conn
+	stop func() bool
+}
+
+func (c *stopConn) Close() error {
+	c.stop()
+	return c.Conn.Close() //nolint:wrapcheck // return underlying error.
+}
+
+// roundTrip writes the GET request to conn and reads the response status and
+// headers, returning the status code, content length (-1 if unknown) and a
+// reader for the response body.
+func roundTrip(conn net.Conn, u *url.URL) (int, int64, io.Reader, error) {
+	req := "GET " + u.RequestURI() + " HTTP/1.1\r\n" +
+		"Host: " + u.Host + "\r\n" +
+		"User-Agent: gomaxecs\r\n" +
+		"Accept: application/json\r\n" +
+		"Connection: close\r\n\r\n"
+
+	if _, err := io.WriteString(conn, req); err != nil {
+		return 0, 0, nil, err //nolint:wrapcheck // wrapped by caller.
+	}
+
+	br := bufio.NewReader(conn)
+	header := &limitedLineReader{br: br, remaining: maxHeaderBytes}
+
+	status, err := readStatus(header)
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	contentLength, chunked := int64(-1), false
+
+	for {
+		line, err := header.readLine()
+		if err != nil {
+			return 0, 0, nil, err
+		}
+
+		if line == "" {
+			break
+		}
+
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return 0, 0, nil, errMalformedResponse
+		}
+
+		value = strings.TrimSpace(value)
+
+		switch strings.ToLower(name) {
+		case "content-length":
+			contentLength, err = strconv.ParseInt(value, 10, 64)
+			if err != nil || contentLength < 0 {
+				return 0, 0, nil, errMalformedResponse
+			}
+		case "transfer-encoding":
+			chunked = strings.EqualFold(value, "chunked")
+		}
+	}
+
+	var body io.Reader = br
+
+	switch {
+	case chunked:
+		body = &chunkedReader{br: br}
+	case contentLength >= 0:
+		body = &exactReader{r: io.LimitReader(br, contentLength), remaining: contentLength}
+	}
+
+	return status, contentLength, body, nil
+}
+
+func readStatus(header *limitedLineReader) (int, error) {
+	line, err := header.readLine()
+	if err != nil {
+		return 0, err
+	}
+
+	// The status line is of the form HTTP-version SP status-code SP reason-phrase.
+	proto, rest, ok := strings.Cut(line, " ")
+	if !ok || !strings.HasPrefix(proto, "HTTP/1.") {
+		return 0, errMalformedResponse
+	}
+
+	code, _, _ := strings.Cut(rest, " ")
+
+	status, err := strconv.Atoi(code)
+	if err != nil {
+		return 0, errMalformedResponse
+	}
+
+	return status, nil
+}
+
+// limitedLineReader reads CRLF terminated lines, failing once more than
+// remaining bytes have been read.
+type limitedLineReader struct {
+	br        *bufio.Reader
+	remaining int
+}
+
+func (r *limitedLineReader) readLine() (string, error) {
+	var line []byte
+
+	for {
+		chunk, isPrefix, err := r.br.ReadLine()
+		if err != nil {
+			return "", unexpectedEOF(err)
+		}
+
+		r.remaining -= len(chunk)
+		if r.remaining < 0 {
+			return "", errHeaderTooLarge
+		}
+
+		line = append(line, chunk...)
+		if !isPrefix {
+			return string(line), nil
+		}
+	}
+}
+
+// chunkedReader decodes a chunked transfer encoded body.
+type chunkedReader struct {
+	br        *bufio.Reader
+	remaining int64
+	done      bool
+}
+
+func (r *chunkedReader) Read(p []byte) (int, error) {
+	if r.done {
+		return 0, io.EOF
+	}
+
+	if r.remaining == 0 {
+		size, err := r.readChunkSize()
+		if err != nil {
+			return 0, err
+		}
+
+		if size == 0 {
+			r.done = true
+			return 0, io.EOF
+		}
+
+		r.remaining = size
+	}
+
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+
+	n, err := r.br.Read(p)
+	r.remaining -= int64(n)
+
+	if err != nil {
+		return n, unexpectedEOF(err)
+	}
+
+	if r.remaining == 0 {
+		// Each chunk is terminated by CRLF.
+		if _, err := r.br.Discard(len("\r\n")); err != nil {
+			return n, unexpectedEOF(err)
+		}
+	}
+
+	return n, nil
+}
+
+func (r *chunkedReader) readChunkSize() (int64, error) {
+	line := &limitedLineReader{br: r.br, remaining: maxChunkLineLen}
+
+	sizeLine, err := line.readLine()
+	if err != nil {
+		return 0, err
+	}
+
+	// Chunk extensions follow the size, separated by a semicolon.
+	sizeHex, _, _ := strings.Cut(sizeLine, ";")
+
+	size, err := strconv.ParseInt(strings.TrimSpace(sizeHex), 16, 64)
+	if err != nil || size < 0 {
+		return 0, errMalformedResponse
+	}
+
+	return size, nil
+}
+
+// exactReader returns io.ErrUnexpectedEOF if the body is shorter than its content length.
+type exactReader struct {
+	r         io.Reader
+	remaining int64
+}
+
+func (r *exactReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.remaining -= int64(n)
+
+	if errors.Is(err, io.EOF) && r.remaining > 0 {
+		return n, io.ErrUnexpectedEOF
+	}
+
+	return n, err //nolint:wrapcheck // return underlying error.
+}
+
+func unexpectedEOF(err error) error {
+	if errors.Is(err, io.EOF) {
+		return io.ErrUnexpectedEOF
+	}
+
+	return err
+}
+
+// withContextErr returns the context error if ctx is done, as the error caused by
+// the connection deadline does not describe why the deadline was exceeded.
+func withContextErr(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return fmt.Errorf("%w: %w", ctxErr, err)
+	}
+
+	return err
+}
@@ -0,0 +1,113 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+//go:build gomaxecs_lite
+
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rdforte/gomaxecs/internal/client"
+	"github.com/rdforte/gomaxecs/internal/config"
+)
+
+func TestClient_Get_LiteReadsBody(t *testing.T) {
+	t.Parallel()
+
+	tableTest := []struct {
+		name    string
+		body    string
+		chunked bool
+	}{
+		{
+			name: "should read body with content length",
+			body: `{"DockerId":"container-id"}`,
+		},
+		{
+			name:    "should read chunked body",
+			body:    strings.Repeat(`{"DockerId":"container-id"}`, 1<<10),
+			chunked: true,
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if tt.chunked {
+					w.Header().Set("Transfer-Encoding", "chunked")
+				}
+
+				_, err := w.Write([]byte(tt.body))
+				assert.NoError(t, err)
+			}))
+			defer ts.Close()
+
+			c := client.New(config.Client{HTTPTimeout: time.Second, DialTimeout: time.Second})
+
+			res, err := c.Get(context.Background(), ts.URL+"/task")
+			require.NoError(t, err)
+			assert.Equal(t, client.StatusOK, res.StatusCode)
+			assert.Equal(t, tt.body, string(res.Body))
+		})
+	}
+}
+
+func TestClient_Get_LiteBodyTooLarge(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write(make([]byte, 2<<20))
+		assert.NoError(t, err)
+	}))
+	defer ts.Close()
+
+	c := client.New(config.Client{HTTPTimeout: time.Second, DialTimeout: time.Second})
+
+	_, err := c.Get(context.Background(), ts.URL)
+	assert.ErrorIs(t, err, client.ErrResponseTooLarge)
+}
+
+func TestClient_Get_LiteHonorsContextCancellation(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer ts.Close()
+
+	c := client.New(config.Client{HTTPTimeout: 5 * time.Second, DialTimeout: time.Second})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := c.Get(ctx, ts.URL)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -22,9 +22,13 @@ package client_test
 
 import (
 	"context"
+	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -89,3 +93,95 @@ func TestClient_Get_ResBodyFailure(t *testing.T) {
 	require.Error(t, err)
 	assert.Contains(t, err.Error(), "failed to read response body")
 }
+
+func TestClient_Get_ResponseTooLarge(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name          string
+		contentLength bool
+		size          int
+		wantErr       bool
+	}{
+		{name: "content length within limit", contentLength: true, size: 64},
+		{name: "content length over limit", contentLength: true, size: 65, wantErr: true},
+		{name: "chunked within limit", size: 64},
+		{name: "chunked over limit", size: 65, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				if tt.contentLength {
+					w.Header().Set("Content-Length", strconv.Itoa(tt.size))
+				}
+
+				_, err := w.Write(make([]byte, tt.size))
+				assert.NoError(t, err)
+
+				if f, ok := w.(http.Flusher); ok && !tt.contentLength {
+					f.Flush()
+				}
+			}))
+			defer ts.Close()
+
+			c := client.New(config.Client{HTTPTimeout: time.Second, DialTimeout: time.Second, MaxResponseSize: 64})
+
+			res, err := c.Get(context.Background(), ts.URL)
+			if tt.wantErr {
+				require.ErrorIs(t, err, client.ErrResponseTooLarge)
+				assert.Contains(t, err.Error(), "exceeds max size of 64 bytes")
+
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Len(t, res.Body, tt.size)
+		})
+	}
+}
+
+func TestClient_Stream_ReturnsCallbackError(t *testing.T) {
+	t.Parallel()
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer ts.Close()
+
+	c := client.New(config.Client{})
+	wantErr := errors.New("callback failed")
+
+	err := c.Stream(context.Background(), ts.URL, func(status int, _ io.Reader) error {
+		assert.Equal(t, client.StatusOK, status)
+
+		return wantErr
+	})
+	assert.Equal(t, wantErr, err)
+}
+
+// BenchmarkClient_Get measures the cost of a new client performing a metadata
+// request, as done on init. Compare the default net/http client against the
+// lite client by running with -tags gomaxecs_lite.
+func BenchmarkClient_Get(b *testing.B) {
+	body := []byte(`{"Containers":[{"DockerId":"container-id","Limits":{"CPU":2048}}],"Limits":{"CPU":4}}`)
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write(body)
+	}))
+	defer ts.Close()
+
+	cfg := config.New().Client
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for range b.N {
+		c := client.New(cfg)
+		if _, err := c.Get(context.Background(), ts.URL+"/task"); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -22,23 +22,40 @@
 package config
 
 import (
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"log/slog"
 	"os"
 	"strings"
 	"time"
 )
 
 const (
-	metaURIEnv  = "ECS_CONTAINER_METADATA_URI_V4"
-	taskPath    = "/task"
-	httpTimeout = 5
+	metaURIEnv       = "ECS_CONTAINER_METADATA_URI_V4"
+	taskPath         = "/task"
+	taskWithTagsPath = "/taskWithTags" // requires the ECS agent to have tag support enabled.
+	statsPath        = "/stats"
+	httpTimeout      = 5
+	retryBackoff     = 100
 )
 
+// DefaultMaxResponseSize is the default max size in bytes of a metadata response. Task
+// metadata responses are typically a few KB, growing with the number of containers.
+const DefaultMaxResponseSize = 1 << 20
+
 func New(opts ...Option) Config {
 	uri := GetECSMetadataURI()
 
 	cfg := Config{
-		TaskMetadataURI:      uri + taskPath,
-		ContainerMetadataURI: uri,
+		TaskMetadataURI:         uri + taskPath,
+		TaskWithTagsMetadataURI: uri + taskWithTagsPath,
+		ContainerMetadataURI:    uri,
+		ContainerStatsURI:       uri + statsPath,
+		RuntimePolicy:           RuntimeOverride,
+		EnvPolicy:               EnvHonor,
+		CgroupFS:                os.DirFS("/"),
 		Client: Client{
 			HTTPTimeout:           time.Second * httpTimeout,
 			DialTimeout:           time.Second,
@@ -48,6 +65,11 @@ func New(opts ...Option) Config {
 			IdleConnTimeout:       time.Second,
 			TLSHandshakeTimeout:   time.Second,
 			ResponseHeaderTimeout: time.Second,
+			MaxResponseSize:       DefaultMaxResponseSize,
+		},
+		Retry: Retry{
+			MaxRetries: 0,
+			Backoff:    retryBackoff * time.Millisecond,
 		},
 	}
 
@@ -66,10 +88,72 @@ func GetECSMetadataURI() string {
 
 // Config represents the package configuration.
 type Config struct {
-	ContainerMetadataURI string
-	TaskMetadataURI      string
-	Client               Client
-	log                  logger
+	ContainerMetadataURI    string
+	ContainerStatsURI       string
+	TaskMetadataURI         string
+	TaskWithTagsMetadataURI string
+	TaskTags                bool
+	Client                  Client
+	Retry                   Retry
+	Watchdog                Watchdog
+	RuntimePolicy           RuntimePolicy
+	EnvPolicy               EnvPolicy
+	CgroupFS                fs.FS
+	Tuning                  Tuning
+	DryRun                  bool
+	Strict                  bool
+	EMF                     io.Writer
+	Trace                   func(level slog.Level, msg string)
+	InvalidOptions          []error
+	log                     logger
+	slog                    *slog.Logger
+}
+
+// RuntimePolicy determines how GOMAXPROCS is set when the Go runtime is
+// container aware (Go 1.25+) and a cgroup CPU quota exists.
+type RuntimePolicy int
+
+const (
+	// RuntimeOverride sets GOMAXPROCS based on the ECS metadata. This is the default.
+	RuntimeOverride RuntimePolicy = iota
+	// RuntimeDefer leaves GOMAXPROCS to the Go runtime when a cgroup CPU quota exists.
+	RuntimeDefer
+)
+
+func (p RuntimePolicy) String() string {
+	switch p {
+	case RuntimeOverride:
+		return "override"
+	case RuntimeDefer:
+		return "defer"
+	default:
+		return "unknown"
+	}
+}
+
+// EnvPolicy determines how a valid GOMAXPROCS environment variable is treated.
+type EnvPolicy int
+
+const (
+	// EnvHonor leaves GOMAXPROCS as set in the environment. This is the default.
+	EnvHonor EnvPolicy = iota
+	// EnvIgnore ignores the GOMAXPROCS environment variable and sets GOMAXPROCS based on the ECS metadata.
+	EnvIgnore
+	// EnvClamp honors the GOMAXPROCS environment variable but limits it to the ECS CPU limit.
+	EnvClamp
+)
+
+func (p EnvPolicy) String() string {
+	switch p {
+	case EnvHonor:
+		return "honor"
+	case EnvIgnore:
+		return "ignore"
+	case EnvClamp:
+		return "clamp"
+	default:
+		return "unknown"
+	}
 }
 
 type logger func(format string, args ...any)
@@ -84,6 +168,23 @@ type Client struct {
 	IdleConnTimeout       time.Duration
 	TLSHandshakeTimeout   time.Duration
 	ResponseHeaderTimeout time.Duration
+	MaxResponseSize       int64
+}
+
+// Retry represents the retry configuration for metadata requests.
+type Retry struct {
+	// MaxRetries is the max number of times a failed request is retried.
+	MaxRetries int
+	// Backoff is the wait before the first retry, doubling on each subsequent retry.
+	Backoff time.Duration
+}
+
+// Watchdog represents the configuration of the GOMAXPROCS drift watchdog.
+type Watchdog struct {
+	// Interval is the time between checks, or 0 if the watchdog is disabled.
+	Interval time.Duration
+	// Reapply re-applies GOMAXPROCS when it has drifted.
+	Reapply bool
 }
 
 func (c Config) Log(format string, args ...any) {
@@ -92,6 +193,13 @@ func (c Config) Log(format string, args ...any) {
 	}
 }
 
+// LogAttrs logs a message with attributes to the structured logger.
+func (c Config) LogAttrs(level slog.Level, msg string, attrs ...slog.Attr) {
+	if c.slog != nil {
+		c.slog.LogAttrs(context.Background(), level, msg, attrs...)
+	}
+}
+
 // WithLogger sets the logger for the config.
 func WithLogger(logger logger) Option {
 	return func(cfg *Config) {
@@ -99,5 +207,115 @@ func WithLogger(logger logger) Option {
 	}
 }
 
+// WithSlog sets the structured logger for the config.
+func WithSlog(logger *slog.Logger) Option {
+	return func(cfg *Config) {
+		cfg.slog = logger
+	}
+}
+
+// WithLoggerOf sets the loggers of other for the config.
+func WithLoggerOf(other Config) Option {
+	return func(cfg *Config) {
+		cfg.log, cfg.slog = other.log, other.slog
+	}
+}
+
+// WithMaxResponseSize sets the max size in bytes of a metadata response for the config.
+func WithMaxResponseSize(size int64) Option {
+	return func(cfg *Config) {
+		cfg.Client.MaxResponseSize = size
+	}
+}
+
+// WithRetry sets the retry configuration for the config.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(cfg *Config) {
+		cfg.Retry = Retry{MaxRetries: maxRetries, Backoff: backoff}
+	}
+}
+
+// WithWatchdog sets the drift watchdog configuration for the config.
+// Non-positive intervals disable the watchdog.
+func WithWatchdog(interval time.Duration, reapply bool) Option {
+	return func(cfg *Config) {
+		if interval > 0 {
+			cfg.Watchdog = Watchdog{Interval: interval, Reapply: reapply}
+		}
+	}
+}
+
+// WithRuntimePolicy sets the runtime policy for the config.
+func WithRuntimePolicy(policy RuntimePolicy) Option {
+	return func(cfg *Config) {
+		cfg.RuntimePolicy = policy
+	}
+}
+
+// WithEnvPolicy sets the GOMAXPROCS environment variable policy for the config.
+func WithEnvPolicy(policy EnvPolicy) Option {
+	return func(cfg *Config) {
+		cfg.EnvPolicy = policy
+	}
+}
+
+// WithTaskTags enables reading the task metadata including tags for the config.
+func WithTaskTags() Option {
+	return func(cfg *Config) {
+		cfg.TaskTags = true
+	}
+}
+
+// WithDryRun enables dry run for the config.
+func WithDryRun() Option {
+	return func(cfg *Config) {
+		cfg.DryRun = true
+	}
+}
+
+// WithStrict enables strict mode for the config.
+func WithStrict() Option {
+	return func(cfg *Config) {
+		cfg.Strict = true
+	}
+}
+
+// WithEMF sets the writer of the EMF document for the config.
+func WithEMF(w io.Writer) Option {
+	return func(cfg *Config) {
+		cfg.EMF = w
+	}
+}
+
+// WithHeadroom sets the fraction of the CPU limit held back for the config.
+// Values outside of [0, 1) are ignored and recorded in the invalid options.
+func WithHeadroom(headroom float64) Option {
+	return func(cfg *Config) {
+		if !validHeadroom(headroom) {
+			cfg.InvalidOptions = append(cfg.InvalidOptions,
+				fmt.Errorf("invalid %s %v: %w", TuningHeadroom, headroom, errInvalidHeadroom))
+
+			return
+		}
+
+		cfg.Tuning.Headroom = headroom
+	}
+}
+
+// WithMemoryLimitRatio sets the fraction of the memory limit used as the Go memory limit
+// for the config. Values outside of [0, 1] are ignored and recorded in the invalid options.
+func WithMemoryLimitRatio(ratio float64) Option {
+	return func(cfg *Config) {
+		if !validMemoryLimitRatio(ratio) {
+			cfg.InvalidOptions = append(cfg.InvalidOptions,
+				fmt.Errorf("invalid %s %v: %w", TuningMemoryLimitRatio, ratio, errInvalidRatio))
+
+			return
+		}
+
+		cfg.Tuning.MemoryLimitRatio = ratio
+	}
+}
+
 // Option represents a configuration option for the config.
 type Option func(*Config)
@@ -3,10 +3,13 @@ package config_test
 import (
 	"bytes"
 	"log"
+	"log/slog"
+	"os"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/rdforte/gomaxecs/internal/config"
 )
@@ -20,8 +23,13 @@ func TestConfig_New_LoadConfiguration(t *testing.T) {
 
 	wantURI := "mock-ecs-metadata-uri"
 	wantCfg := config.Config{
-		ContainerMetadataURI: wantURI,
-		TaskMetadataURI:      wantURI + "/task",
+		ContainerMetadataURI:    wantURI,
+		ContainerStatsURI:       wantURI + "/stats",
+		TaskMetadataURI:         wantURI + "/task",
+		TaskWithTagsMetadataURI: wantURI + "/taskWithTags",
+		RuntimePolicy:           config.RuntimeOverride,
+		EnvPolicy:               config.EnvHonor,
+		CgroupFS:                os.DirFS("/"),
 		Client: config.Client{
 			HTTPTimeout:           time.Second * 5,
 			DialTimeout:           time.Second,
@@ -31,6 +39,11 @@ func TestConfig_New_LoadConfiguration(t *testing.T) {
 			IdleConnTimeout:       time.Second,
 			TLSHandshakeTimeout:   time.Second,
 			ResponseHeaderTimeout: time.Second,
+			MaxResponseSize:       1 << 20,
+		},
+		Retry: config.Retry{
+			MaxRetries: 0,
+			Backoff:    time.Millisecond * 100,
 		},
 	}
 
@@ -63,6 +76,189 @@ func TestConfig_WithLogger_LogsMessage(t *testing.T) {
 	assert.Equal(t, wantLog, buf.String())
 }
 
+func TestConfig_WithSlog_LogsMessageWithAttrs(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return a
+		},
+	}))
+
+	cfg := config.New(config.WithSlog(logger))
+
+	cfg.LogAttrs(slog.LevelInfo, "test log", slog.Int("procs", 2))
+
+	wantLog := "level=INFO msg=\"test log\" procs=2\n"
+	assert.Equal(t, wantLog, buf.String())
+}
+
+func TestConfig_WithMaxResponseSize_SetsMaxResponseSize(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.New(config.WithMaxResponseSize(1 << 10))
+
+	assert.Equal(t, int64(1<<10), cfg.Client.MaxResponseSize)
+}
+
+func TestConfig_WithRetry_SetsRetry(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.New(config.WithRetry(3, time.Second))
+
+	want := config.Retry{MaxRetries: 3, Backoff: time.Second}
+	assert.Equal(t, want, cfg.Retry)
+}
+
+func TestConfig_WithWatchdog_SetsWatchdog(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.New(config.WithWatchdog(time.Minute, true))
+
+	want := config.Watchdog{Interval: time.Minute, Reapply: true}
+	assert.Equal(t, want, cfg.Watchdog)
+	assert.Equal(t, config.Watchdog{}, config.New(config.WithWatchdog(0, true)).Watchdog)
+}
+
+func TestConfig_WithRuntimePolicy_SetsRuntimePolicy(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.New(config.WithRuntimePolicy(config.RuntimeDefer))
+
+	assert.Equal(t, config.RuntimeDefer, cfg.RuntimePolicy)
+}
+
+func TestConfig_WithEnvPolicy_SetsEnvPolicy(t *testing.T) {
+	t.Parallel()
+
+	cfg := config.New(config.WithEnvPolicy(config.EnvClamp))
+
+	assert.Equal(t, config.EnvClamp, cfg.EnvPolicy)
+}
+
+func TestConfig_WithTaskTags_EnablesTaskTags(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, config.New().TaskTags)
+	assert.True(t, config.New(config.WithTaskTags()).TaskTags)
+}
+
+func TestConfig_WithDryRun_EnablesDryRun(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, config.New().DryRun)
+	assert.True(t, config.New(config.WithDryRun()).DryRun)
+}
+
+func TestConfig_WithStrict_EnablesStrict(t *testing.T) {
+	t.Parallel()
+
+	assert.False(t, config.New().Strict)
+	assert.True(t, config.New(config.WithStrict()).Strict)
+}
+
+func TestConfig_Policy_String(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "override", config.RuntimeOverride.String())
+	assert.Equal(t, "defer", config.RuntimeDefer.String())
+	assert.Equal(t, "honor", config.EnvHonor.String())
+	assert.Equal(t, "ignore", config.EnvIgnore.String())
+	assert.Equal(t, "clamp", config.EnvClamp.String())
+	assert.Equal(t, "unknown", config.EnvPolicy(-1).String())
+}
+
+func TestConfig_WithEMF_SetsEMFWriter(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+
+	assert.Nil(t, config.New().EMF)
+	assert.Equal(t, buf, config.New(config.WithEMF(buf)).EMF)
+}
+
+func TestConfig_WithHeadroom_SetsHeadroom(t *testing.T) {
+	t.Parallel()
+
+	assert.InDelta(t, 0.25, config.New(config.WithHeadroom(0.25)).Tuning.Headroom, 0)
+	assert.InDelta(t, 0, config.New(config.WithHeadroom(1)).Tuning.Headroom, 0)
+	assert.Empty(t, config.New(config.WithHeadroom(0.25)).InvalidOptions)
+
+	invalid := config.New(config.WithHeadroom(1)).InvalidOptions
+	require.Len(t, invalid, 1)
+	assert.EqualError(t, invalid[0], "invalid headroom 1: must be a number from 0 up to but excluding 1")
+}
+
+func TestConfig_WithMemoryLimitRatio_SetsMemoryLimitRatio(t *testing.T) {
+	t.Parallel()
+
+	assert.InDelta(t, 0.9, config.New(config.WithMemoryLimitRatio(0.9)).Tuning.MemoryLimitRatio, 0)
+	assert.InDelta(t, 0, config.New(config.WithMemoryLimitRatio(-0.5)).Tuning.MemoryLimitRatio, 0)
+
+	invalid := config.New(config.WithMemoryLimitRatio(-0.5)).InvalidOptions
+	require.Len(t, invalid, 1)
+	assert.EqualError(t, invalid[0], "invalid memlimit-ratio -0.5: must be a number from 0 to 1")
+}
+
+func TestConfig_Tuning_Set(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		key        string
+		value      string
+		wantTuning config.Tuning
+		wantErr    string
+	}{
+		{name: "procs", key: config.TuningProcs, value: "4", wantTuning: config.Tuning{Procs: 4}},
+		{name: "headroom", key: config.TuningHeadroom, value: "0.5", wantTuning: config.Tuning{Headroom: 0.5}},
+		{name: "disable", key: config.TuningDisable, value: "true", wantTuning: config.Tuning{Disable: true}},
+		{
+			name: "memory limit ratio", key: config.TuningMemoryLimitRatio, value: "1",
+			wantTuning: config.Tuning{MemoryLimitRatio: 1},
+		},
+		{
+			name: "invalid procs", key: config.TuningProcs, value: "-1",
+			wantErr: `invalid procs "-1": must be a non-negative integer`,
+		},
+		{
+			name: "invalid headroom", key: config.TuningHeadroom, value: "1",
+			wantErr: `invalid headroom "1": must be a number from 0 up to but excluding 1`,
+		},
+		{
+			name: "invalid disable", key: config.TuningDisable, value: "maybe",
+			wantErr: `invalid disable "maybe": must be a boolean`,
+		},
+		{
+			name: "invalid memory limit ratio", key: config.TuningMemoryLimitRatio, value: "NaN",
+			wantErr: `invalid memlimit-ratio "NaN": must be a number from 0 to 1`,
+		},
+		{name: "unknown key", key: "unknown", value: "1", wantErr: `unknown key "unknown"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			var tuning config.Tuning
+
+			err := tuning.Set(tt.key, tt.value)
+			if tt.wantErr != "" {
+				require.EqualError(t, err, tt.wantErr)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantTuning, tuning)
+		})
+	}
+}
+
 func TestConfig_GetECSMetadataURI_RetrievesMetadataURIFromEnv(t *testing.T) {
 	metaURIEnv := "ECS_CONTAINER_METADATA_URI_V4"
 	uri := "mock-ecs-metadata-uri/"
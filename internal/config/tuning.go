@@ -0,0 +1,136 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// TuningPrefix is the prefix of the container labels and task tags which override the tuning.
+const TuningPrefix = "gomaxecs."
+
+// The tuning keys, as used in container labels and task tags following TuningPrefix.
+const (
+	TuningProcs            = "procs"
+	TuningHeadroom         = "headroom"
+	TuningDisable          = "disable"
+	TuningMemoryLimitRatio = "memlimit-ratio"
+)
+
+var (
+	errUnknownTuningKey = errors.New("unknown key")
+	errInvalidProcs     = errors.New("must be a non-negative integer")
+	errInvalidHeadroom  = errors.New("must be a number from 0 up to but excluding 1")
+	errInvalidRatio     = errors.New("must be a number from 0 to 1")
+	errInvalidDisable   = errors.New("must be a boolean")
+)
+
+// Tuning represents the settings which can be overridden per container.
+type Tuning struct {
+	// Procs is a fixed value for GOMAXPROCS. When 0, GOMAXPROCS is resolved from the CPU limits.
+	Procs int
+	// Headroom is the fraction of the CPU limit held back when resolving GOMAXPROCS.
+	Headroom float64
+	// Disable leaves GOMAXPROCS and the memory limit unchanged.
+	Disable bool
+	// MemoryLimitRatio is the fraction of the memory limit used as the Go memory limit.
+	// When 0, the Go memory limit is left unchanged.
+	MemoryLimitRatio float64
+}
+
+// Set sets the tuning key to the value, returning an error if the key is unknown
+// or the value is invalid, in which case the tuning is unchanged.
+func (t *Tuning) Set(key, value string) error {
+	var err error
+
+	switch key {
+	case TuningProcs:
+		err = t.setProcs(value)
+	case TuningHeadroom:
+		err = t.setHeadroom(value)
+	case TuningDisable:
+		err = t.setDisable(value)
+	case TuningMemoryLimitRatio:
+		err = t.setMemoryLimitRatio(value)
+	default:
+		return fmt.Errorf("%w %q", errUnknownTuningKey, key)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid %s %q: %w", key, value, err)
+	}
+
+	return nil
+}
+
+func (t *Tuning) setProcs(value string) error {
+	procs, err := strconv.Atoi(value)
+	if err != nil || procs < 0 {
+		return errInvalidProcs
+	}
+
+	t.Procs = procs
+
+	return nil
+}
+
+func (t *Tuning) setHeadroom(value string) error {
+	headroom, err := strconv.ParseFloat(value, 64)
+	if err != nil || !validHeadroom(headroom) {
+		return errInvalidHeadroom
+	}
+
+	t.Headroom = headroom
+
+	return nil
+}
+
+func (t *Tuning) setDisable(value string) error {
+	disable, err := strconv.ParseBool(value)
+	if err != nil {
+		return errInvalidDisable
+	}
+
+	t.Disable = disable
+
+	return nil
+}
+
+func (t *Tuning) setMemoryLimitRatio(value string) error {
+	ratio, err := strconv.ParseFloat(value, 64)
+	if err != nil || !validMemoryLimitRatio(ratio) {
+		return errInvalidRatio
+	}
+
+	t.MemoryLimitRatio = ratio
+
+	return nil
+}
+
+func validHeadroom(headroom float64) bool {
+	return headroom >= 0 && headroom < 1
+}
+
+func validMemoryLimitRatio(ratio float64) bool {
+	return ratio >= 0 && ratio <= 1
+}
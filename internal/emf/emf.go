@@ -0,0 +1,128 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package emf provides writing metrics in the CloudWatch Embedded Metric Format (EMF).
+// EMF documents written to the logs of an ECS task using the awslogs log driver are
+// extracted as CloudWatch metrics, without running an agent.
+package emf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The units of the metrics.
+const (
+	UnitCount     = "Count"
+	UnitNone      = "None"
+	UnitMegabytes = "Megabytes"
+)
+
+// Dimension is a dimension of the metrics.
+type Dimension struct {
+	Name  string
+	Value string
+}
+
+// Metric is a metric value.
+type Metric struct {
+	Name  string
+	Unit  string
+	Value float64
+}
+
+// Document is an EMF document.
+type Document struct {
+	Namespace  string
+	Timestamp  time.Time
+	Dimensions []Dimension
+	Metrics    []Metric
+	// Properties are included in the document without being metrics or dimensions,
+	// and can be queried with CloudWatch Logs Insights.
+	Properties map[string]string
+}
+
+type metadata struct {
+	Timestamp         int64             `json:"Timestamp"`
+	CloudWatchMetrics []metricDirective `json:"CloudWatchMetrics"`
+}
+
+type metricDirective struct {
+	Namespace  string             `json:"Namespace"`
+	Dimensions [][]string         `json:"Dimensions"`
+	Metrics    []metricDefinition `json:"Metrics"`
+}
+
+type metricDefinition struct {
+	Name string `json:"Name"`
+	Unit string `json:"Unit"`
+}
+
+// Write writes the document to w as a single line of JSON. Dimensions and properties
+// with an empty value are omitted, as CloudWatch rejects empty dimension values.
+func Write(w io.Writer, doc Document) error {
+	root := make(map[string]any, len(doc.Properties)+len(doc.Dimensions)+len(doc.Metrics)+1)
+
+	for name, value := range doc.Properties {
+		if value != "" {
+			root[name] = value
+		}
+	}
+
+	dimensions := make([]string, 0, len(doc.Dimensions))
+
+	for _, dim := range doc.Dimensions {
+		if dim.Value == "" {
+			continue
+		}
+
+		dimensions = append(dimensions, dim.Name)
+		root[dim.Name] = dim.Value
+	}
+
+	definitions := make([]metricDefinition, 0, len(doc.Metrics))
+
+	for _, metric := range doc.Metrics {
+		definitions = append(definitions, metricDefinition{Name: metric.Name, Unit: metric.Unit})
+		root[metric.Name] = metric.Value
+	}
+
+	root["_aws"] = metadata{
+		Timestamp: doc.Timestamp.UnixMilli(),
+		CloudWatchMetrics: []metricDirective{{
+			Namespace:  doc.Namespace,
+			Dimensions: [][]string{dimensions},
+			Metrics:    definitions,
+		}},
+	}
+
+	b, err := json.Marshal(root)
+	if err != nil {
+		return fmt.Errorf("failed to marshal EMF document: %w", err)
+	}
+
+	if _, err := w.Write(append(b, '\n')); err != nil {
+		return fmt.Errorf("failed to write EMF document: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,120 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package emf_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rdforte/gomaxecs/internal/emf"
+)
+
+func TestEMF_Write(t *testing.T) {
+	t.Parallel()
+
+	timestamp := time.UnixMilli(1700000000000)
+
+	tableTest := []struct {
+		name    string
+		doc     emf.Document
+		wantDoc string
+	}{
+		{
+			name: "should write metrics with dimensions and properties",
+			doc: emf.Document{
+				Namespace: "gomaxecs",
+				Timestamp: timestamp,
+				Dimensions: []emf.Dimension{
+					{Name: "ClusterName", Value: "default"},
+					{Name: "TaskFamily", Value: "service"},
+				},
+				Metrics: []emf.Metric{
+					{Name: "GOMAXPROCS", Unit: emf.UnitCount, Value: 2},
+					{Name: "TaskMemory", Unit: emf.UnitMegabytes, Value: 512},
+				},
+				Properties: map[string]string{"Source": "ecs"},
+			},
+			wantDoc: `{"_aws":{"Timestamp":1700000000000,"CloudWatchMetrics":[{"Namespace":"gomaxecs",` +
+				`"Dimensions":[["ClusterName","TaskFamily"]],` +
+				`"Metrics":[{"Name":"GOMAXPROCS","Unit":"Count"},{"Name":"TaskMemory","Unit":"Megabytes"}]}]},` +
+				`"ClusterName":"default","TaskFamily":"service","GOMAXPROCS":2,"TaskMemory":512,"Source":"ecs"}`,
+		},
+		{
+			name: "should omit dimensions and properties with empty value",
+			doc: emf.Document{
+				Namespace: "gomaxecs",
+				Timestamp: timestamp,
+				Dimensions: []emf.Dimension{
+					{Name: "ClusterName", Value: "default"},
+					{Name: "ServiceName", Value: ""},
+				},
+				Metrics:    []emf.Metric{{Name: "GOMAXPROCS", Unit: emf.UnitCount, Value: 1}},
+				Properties: map[string]string{"TaskARN": ""},
+			},
+			wantDoc: `{"_aws":{"Timestamp":1700000000000,"CloudWatchMetrics":[{"Namespace":"gomaxecs",` +
+				`"Dimensions":[["ClusterName"]],"Metrics":[{"Name":"GOMAXPROCS","Unit":"Count"}]}]},` +
+				`"ClusterName":"default","GOMAXPROCS":1}`,
+		},
+		{
+			name: "should write empty dimension set when no dimensions",
+			doc: emf.Document{
+				Namespace: "gomaxecs",
+				Timestamp: timestamp,
+				Metrics:   []emf.Metric{{Name: "GOMAXPROCS", Unit: emf.UnitCount, Value: 1}},
+			},
+			wantDoc: `{"_aws":{"Timestamp":1700000000000,"CloudWatchMetrics":[{"Namespace":"gomaxecs",` +
+				`"Dimensions":[[]],"Metrics":[{"Name":"GOMAXPROCS","Unit":"Count"}]}]},"GOMAXPROCS":1}`,
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			require.NoError(t, emf.Write(buf, tt.doc))
+
+			assert.JSONEq(t, tt.wantDoc, buf.String())
+			assert.Equal(t, 1, bytes.Count(buf.Bytes(), []byte("\n")))
+		})
+	}
+}
+
+func TestEMF_Write_ReturnsWriteError(t *testing.T) {
+	t.Parallel()
+
+	err := emf.Write(errWriter{}, emf.Document{Namespace: "gomaxecs"})
+	require.ErrorIs(t, err, errWrite)
+	assert.ErrorContains(t, err, "failed to write EMF document")
+}
+
+var errWrite = errors.New("write failed")
+
+type errWriter struct{}
+
+func (errWriter) Write([]byte) (int, error) {
+	return 0, errWrite
+}
@@ -0,0 +1,54 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package maxprocstest provides helpers for testing packages built on the active
+// maxprocs.Handle.
+package maxprocstest
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+	"github.com/rdforte/gomaxecs/internal/task/tasktest"
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+// SetGOMAXPROCS sets GOMAXPROCS to 2 with opts, from a test ECS agent serving the metadata
+// of the <app> container of the service:3 task, resetting it on cleanup.
+func SetGOMAXPROCS(t testing.TB, opts ...config.Option) {
+	t.Helper()
+
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(`{"DockerId":"container-id","Name":"<app>","Limits":{"CPU":2048}}`).
+		WithTaskMetaEndpointJSON(`{"Family":"service","Revision":"3",` +
+			`"Containers":[{"DockerId":"container-id","Limits":{"CPU":2048}}],"Limits":{"CPU":4}}`).
+		Start().
+		SetMetaURIEnv()
+	t.Cleanup(agent.Close)
+
+	undo, err := maxprocs.Set(opts...)
+	require.NoError(t, err)
+	t.Cleanup(undo)
+}
@@ -0,0 +1,117 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package startup provides the setting of GOMAXPROCS performed when importing
+// the gomaxecs packages for their side effects.
+package startup
+
+import (
+	"context"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+const (
+	logFormatEnv  = "GOMAXECS_LOG_FORMAT"
+	logFormatJSON = "json"
+	logFormatText = "text"
+	taskTagsEnv   = "GOMAXECS_TASK_TAGS"
+	dryRunEnv     = "GOMAXECS_DRY_RUN"
+	strictEnv     = "GOMAXECS_STRICT"
+	emfEnv        = "GOMAXECS_EMF"
+)
+
+// Run sets GOMAXPROCS if an ECS environment is detected. Returns a function to
+// reset GOMAXPROCS and an error if GOMAXPROCS could not be resolved.
+func Run(ctx context.Context) (func(), error) {
+	logger := loggerOption()
+
+	if !maxprocs.IsECS() {
+		cfg := config.New(logger)
+		cfg.Log("gomaxecs: ECS environment not detected. Skipping set GOMAXPROCS")
+		cfg.LogAttrs(slog.LevelInfo, "gomaxecs: ECS environment not detected. Skipping set GOMAXPROCS")
+
+		return func() {}, nil
+	}
+
+	return maxprocs.SetContext(ctx, options(logger)...) //nolint:wrapcheck // error is already descriptive.
+}
+
+// options returns the options set in the GOMAXECS_ environment variables.
+func options(logger config.Option) []config.Option {
+	opts := []config.Option{logger}
+
+	if envBool(logger, taskTagsEnv) {
+		opts = append(opts, maxprocs.WithTaskTags())
+	}
+
+	if envBool(logger, dryRunEnv) {
+		opts = append(opts, maxprocs.WithDryRun())
+	}
+
+	if envBool(logger, strictEnv) {
+		opts = append(opts, maxprocs.WithStrict())
+	}
+
+	if envBool(logger, emfEnv) {
+		opts = append(opts, maxprocs.WithEMF(os.Stdout))
+	}
+
+	return opts
+}
+
+// envBool returns true if the environment variable is set to a true boolean value.
+// Invalid values are reported and treated as false.
+func envBool(logger config.Option, key string) bool {
+	env, ok := os.LookupEnv(key)
+	if !ok {
+		return false
+	}
+
+	b, err := strconv.ParseBool(env)
+	if err != nil {
+		cfg := config.New(logger)
+		cfg.Log("gomaxecs: Ignoring invalid %s=%q, must be a boolean", key, env)
+		cfg.LogAttrs(slog.LevelWarn, "gomaxecs: Ignoring invalid environment variable, must be a boolean",
+			slog.String("env", key), slog.String("value", env))
+
+		return false
+	}
+
+	return b
+}
+
+// loggerOption returns the logger option for the log format set in the
+// GOMAXECS_LOG_FORMAT environment variable.
+func loggerOption() config.Option {
+	switch os.Getenv(logFormatEnv) {
+	case logFormatJSON:
+		return maxprocs.WithSlog(slog.New(slog.NewJSONHandler(os.Stderr, nil)))
+	case logFormatText:
+		return maxprocs.WithSlog(slog.New(slog.NewTextHandler(os.Stderr, nil)))
+	default:
+		return maxprocs.WithLogger(log.Printf)
+	}
+}
@@ -21,36 +21,145 @@
 package task
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"net/http"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/rdforte/gomaxecs/internal/client"
 )
 
 // taskMeta represents the ECS Task Metadata.
 type taskMeta struct {
-	Containers []container `json:"Containers"`
-	Limits     limit       `json:"Limits"` // this is optional in the response
+	Cluster          string      `json:"Cluster"`
+	TaskARN          string      `json:"TaskARN"`
+	Family           string      `json:"Family"`
+	Revision         string      `json:"Revision"`
+	ServiceName      string      `json:"ServiceName"`
+	LaunchType       string      `json:"LaunchType"`
+	AvailabilityZone string      `json:"AvailabilityZone"`
+	Containers       []container `json:"Containers"`
+	Limits           limit       `json:"Limits"` // this is optional in the response
+	// TaskTags and ContainerInstanceTags are only present in the task metadata with tags.
+	TaskTags              map[string]string `json:"TaskTags"`
+	ContainerInstanceTags map[string]string `json:"ContainerInstanceTags"`
+	// Errors are the tags the ECS agent failed to get, only present in the task metadata with tags.
+	Errors []tagsError `json:"Errors"`
+	// tagsErr is the reason the tags are unavailable when requested.
+	tagsErr error
+}
+
+// tagsError represents an error getting tags in the ECS Task Metadata with tags.
+type tagsError struct {
+	ErrorField   string `json:"ErrorField"`
+	ErrorCode    string `json:"ErrorCode"`
+	ErrorMessage string `json:"ErrorMessage"`
+}
+
+func (e tagsError) String() string {
+	return fmt.Sprintf("%s: %s: %s", e.ErrorField, e.ErrorCode, e.ErrorMessage)
 }
 
 // container represents the ECS Container Metadata.
 type container struct {
 	//nolint:tagliatelle // ECS Agent inconsistency. All fields adhere to goPascal but this one.
-	DockerID string `json:"DockerId"`
-	Limits   limit  `json:"Limits"`
+	DockerID     string            `json:"DockerId"`
+	Name         string            `json:"Name"`
+	ContainerARN string            `json:"ContainerARN"`
+	Labels       map[string]string `json:"Labels"`
+	Limits       limit             `json:"Limits"`
 }
 
-// limit contains the CPU limit.
+// match returns how the container matches other, or an empty string if it does not.
+// The Docker ID is preferred, falling back to the container ARN and then the name,
+// which is unique within a task. Empty identifiers never match.
+func (c container) match(other container) string {
+	switch {
+	case c.DockerID != "" && c.DockerID == other.DockerID:
+		return MatchDockerID
+	case c.ContainerARN != "" && c.ContainerARN == other.ContainerARN:
+		return MatchContainerARN
+	case c.Name != "" && c.Name == other.Name:
+		return MatchName
+	default:
+		return ""
+	}
+}
+
+// limit contains the CPU and memory limits.
 type limit struct {
-	CPU float64 `json:"CPU"`
+	CPU    limitValue `json:"CPU"`
+	Memory limitValue `json:"Memory"`
+}
+
+// limitValue is a limit decoded leniently, as agent and Fargate versions differ in how
+// limits are encoded. Numbers and numeric strings are accepted and null or empty strings
+// are treated as not set. Any other value is recorded as invalid rather than failing the
+// decode, so the remaining limits can still be used.
+type limitValue struct {
+	value   float64
+	invalid string
+}
+
+func (v *limitValue) UnmarshalJSON(b []byte) error {
+	*v = limitValue{}
+
+	raw := strings.TrimSpace(string(b))
+	if raw == "null" || raw == `""` {
+		return nil
+	}
+
+	num := raw
+	if s, err := strconv.Unquote(raw); err == nil {
+		num = strings.TrimSpace(s)
+	}
+
+	value, err := strconv.ParseFloat(num, 64)
+	if err != nil || value < 0 || math.IsInf(value, 0) || math.IsNaN(value) {
+		v.invalid = raw
+		return nil
+	}
+
+	v.value = value
+
+	return nil
+}
+
+// unusable returns the description of the limit fields which were set but invalid,
+// prefixed with the given source of the limits.
+func (l limit) unusable(source string) []string {
+	var fields []string
+
+	for _, f := range []struct {
+		name  string
+		value limitValue
+	}{
+		{"CPU", l.CPU},
+		{"Memory", l.Memory},
+	} {
+		if f.value.invalid != "" {
+			fields = append(fields, fmt.Sprintf("%s Limits.%s=%s", source, f.name, f.value.invalid))
+		}
+	}
+
+	return fields
 }
 
 // Grab the container metadata from the ECS Metadata endpoint.
 // https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-metadata-endpoint-v4-examples.html
 func (t *Task) getContainerMeta(ctx context.Context) (container, error) {
-	return getMeta[container](ctx, t.client, t.containerMetadataURI)
+	meta, raw, err := getMetaWithRetry[container](ctx, t, t.containerMetadataURI)
+	if err == nil {
+		t.raw.set(&t.raw.container, raw)
+	}
+
+	return meta, err
 }
 
 // Grab the task metadata from the ECS Metadata endpoint + `/task`
@@ -58,27 +167,200 @@ func (t *Task) getContainerMeta(ctx context.Context) (container, error) {
 // https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-metadata-endpoint-v4-examples.html
 // #task-metadata-endpoint-v4-example-task-metadata-response.
 func (t *Task) getTaskMeta(ctx context.Context) (taskMeta, error) {
-	return getMeta[taskMeta](ctx, t.client, t.taskMetadataURI)
+	meta, raw, err := getMetaWithRetry[taskMeta](ctx, t, t.taskMetadataURI)
+	if err == nil {
+		t.raw.set(&t.raw.task, raw)
+	}
+
+	return meta, err
 }
 
-func getMeta[T any](ctx context.Context, client *client.Client, url string) (T, error) {
-	var res T
+// Grab the task metadata including the task and container instance tags from the ECS
+// Metadata endpoint + `/taskWithTags`, when enabled. If the tags are unavailable, such as
+// when the ECS agent does not have tag support enabled, fall back to the task metadata
+// recording the reason the tags are unavailable.
+// https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-metadata-endpoint-v4-response.html
+func (t *Task) getTaskMetaWithTags(ctx context.Context) (taskMeta, error) {
+	if !t.taskTags {
+		return t.getTaskMeta(ctx)
+	}
 
-	resp, err := client.Get(ctx, url)
-	if err != nil {
-		return res, fmt.Errorf("request failed: %w", err)
+	meta, raw, tagsErr := getMetaWithRetry[taskMeta](ctx, t, t.taskWithTagsMetadataURI)
+	if tagsErr == nil {
+		t.raw.set(&t.raw.task, raw)
+	}
+
+	// The ECS agent responds with the tags it could get, alongside the errors for the others.
+	if tagsErr == nil && len(meta.Errors) > 0 {
+		errs := make([]string, 0, len(meta.Errors))
+		for _, e := range meta.Errors {
+			errs = append(errs, e.String())
+		}
+
+		meta.tagsErr = fmt.Errorf("%w: %s", ErrTagsIncomplete, strings.Join(errs, "; "))
 	}
 
-	if resp.StatusCode != http.StatusOK {
-		return res, newStatusError(resp.StatusCode)
+	if tagsErr == nil || ctx.Err() != nil {
+		return meta, tagsErr
 	}
 
-	err = json.Unmarshal(resp.Body, &res)
+	meta, err := t.getTaskMeta(ctx)
+	meta.tagsErr = fmt.Errorf("task tags unavailable: %w", tagsErr)
+
+	return meta, err
+}
+
+// containerStats represents the Docker stats of the container, of which only
+// the CPU throttling data is used.
+type containerStats struct {
+	CPUStats struct {
+		ThrottlingData struct {
+			Periods          uint64 `json:"periods"`
+			ThrottledPeriods uint64 `json:"throttled_periods"` //nolint:tagliatelle // Docker stats are snake case.
+			ThrottledTime    uint64 `json:"throttled_time"`    //nolint:tagliatelle // Docker stats are snake case.
+		} `json:"throttling_data"` //nolint:tagliatelle // Docker stats are snake case.
+	} `json:"cpu_stats"` //nolint:tagliatelle // Docker stats are snake case.
+}
+
+// Grab the container stats from the ECS Metadata endpoint + `/stats`.
+// https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-metadata-endpoint-v4-examples.html
+// #task-metadata-endpoint-v4-example-container-stats-response.
+func (t *Task) getContainerStats(ctx context.Context) (containerStats, error) {
+	stats, _, err := getMetaWithRetry[containerStats](ctx, t, t.containerStatsURI)
+	return stats, err
+}
+
+// getMetaWithRetry gets the metadata and the raw response, retrying failed requests and
+// server errors with an exponential backoff, counting the retries of the task. Retries
+// stop as soon as ctx is done.
+func getMetaWithRetry[T any](ctx context.Context, t *Task, url string) (T, []byte, error) {
+	backoff := t.retry.Backoff
+
+	for attempt := 0; ; attempt++ {
+		res, raw, err := getMeta[T](ctx, t.client, url)
+		if err == nil || attempt >= t.retry.MaxRetries || !isRetryable(err) || ctx.Err() != nil {
+			return res, raw, err
+		}
+
+		if err := sleep(ctx, backoff); err != nil {
+			return res, nil, fmt.Errorf("retry canceled: %w", err)
+		}
+
+		t.retries.Add(1)
+
+		backoff *= 2
+	}
+}
+
+// isRetryable returns true if the request failed or the server responded with
+// a server error or too many requests.
+func isRetryable(err error) bool {
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.status >= client.StatusInternalServerError || statusErr.status == client.StatusTooManyRequests
+	}
+
+	var reqErr *requestError
+
+	return errors.As(err, &reqErr)
+}
+
+// sleep waits for d, returning early with the context error if ctx is done.
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// getMeta gets the metadata, decoding the response as it is read, and the raw response.
+func getMeta[T any](ctx context.Context, c *client.Client, url string) (T, []byte, error) {
+	var (
+		res       T
+		raw       bytes.Buffer
+		decodeErr error
+	)
+
+	err := c.Stream(ctx, url, func(status int, body io.Reader) error {
+		if status != client.StatusOK {
+			return newStatusError(status)
+		}
+
+		r := &errCapturingReader{r: io.TeeReader(body, &raw)}
+		dec := json.NewDecoder(r)
+
+		decodeErr = dec.Decode(&res)
+		if decodeErr == nil {
+			decodeErr = checkEOF(dec, r)
+		}
+
+		// Errors reading the body take precedence over the resulting decode error.
+		if r.err != nil {
+			return r.err
+		}
+
+		return nil
+	})
+
+	switch {
+	case errors.Is(err, client.ErrResponseTooLarge):
+		return res, nil, fmt.Errorf("metadata %w", err)
+	case errors.As(err, new(*statusError)):
+		return res, nil, err
+	case err != nil:
+		return res, nil, &requestError{err}
+	case decodeErr != nil:
+		return res, nil, fmt.Errorf("%w: %w", ErrMalformedMetadata, decodeErr)
+	}
+
+	return res, raw.Bytes(), nil
+}
+
+// checkEOF returns an error if anything other than whitespace follows the decoded value.
+func checkEOF(dec *json.Decoder, r io.Reader) error {
+	rest, err := io.ReadAll(io.MultiReader(dec.Buffered(), r))
 	if err != nil {
-		return res, fmt.Errorf("unmarshal failed: %w", err)
+		return err //nolint:wrapcheck // the read error is captured and returned instead.
+	}
+
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return errTrailingData
+	}
+
+	return nil
+}
+
+// errCapturingReader records the first non EOF error returned by the underlying reader,
+// distinguishing failures reading the response from malformed JSON.
+type errCapturingReader struct {
+	r   io.Reader
+	err error
+}
+
+func (r *errCapturingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	if err != nil && !errors.Is(err, io.EOF) && r.err == nil {
+		r.err = err
 	}
 
-	return res, nil
+	return n, err //nolint:wrapcheck // return underlying error.
+}
+
+type requestError struct {
+	err error
+}
+
+func (e *requestError) Error() string {
+	return fmt.Sprintf("request failed: %v", e.err)
+}
+
+func (e *requestError) Unwrap() error {
+	return e.err
 }
 
 func newStatusError(status int) error {
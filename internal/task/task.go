@@ -26,32 +26,201 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rdforte/gomaxecs/internal/client"
 	"github.com/rdforte/gomaxecs/internal/config"
 )
 
 const (
-	cpuUnits = 10
-	minCPU   = 1
+	cpuUnitsShift = 10 // 1024 CPU units is 1 vCPU.
+	minCPU        = 1
+	arnPrefix     = "arn:"
 )
 
-var errNoCPULimit = errors.New("no CPU limit found for task or container")
+// The identifiers used to match the container in the task metadata.
+const (
+	MatchDockerID     = "DockerId"
+	MatchContainerARN = "ContainerARN"
+	MatchName         = "Name"
+)
+
+var (
+	errNoCPULimit   = errors.New("no CPU limit found for task or container")
+	errTrailingData = errors.New("unexpected data after top-level value")
+)
+
+// ErrMalformedMetadata is wrapped by the error when a metadata response is not valid JSON,
+// or has data following the JSON value.
+var ErrMalformedMetadata = errors.New("unmarshal failed")
+
+// ErrTagsIncomplete is wrapped by Limits.TagsErr when the task metadata with tags is
+// returned with errors for some of the tags, in which case the tags returned are used.
+var ErrTagsIncomplete = errors.New("task tags incomplete")
 
 // Task represents a task.
 type Task struct {
-	taskMetadataURI      string
-	containerMetadataURI string
-	client               *client.Client
+	taskMetadataURI         string
+	taskWithTagsMetadataURI string
+	containerMetadataURI    string
+	containerStatsURI       string
+	taskTags                bool
+	client                  *client.Client
+	retry                   config.Retry
+	retries                 atomic.Uint64
+	raw                     rawMetadata
 }
 
 // New returns a new Task.
 func New(cfg config.Config) *Task {
 	return &Task{
-		cfg.TaskMetadataURI,
-		cfg.ContainerMetadataURI,
-		client.New(cfg.Client),
+		taskMetadataURI:         cfg.TaskMetadataURI,
+		taskWithTagsMetadataURI: cfg.TaskWithTagsMetadataURI,
+		containerMetadataURI:    cfg.ContainerMetadataURI,
+		containerStatsURI:       cfg.ContainerStatsURI,
+		taskTags:                cfg.TaskTags,
+		client:                  client.New(cfg.Client),
+		retry:                   cfg.Retry,
+	}
+}
+
+// Retries returns the number of metadata requests retried by the task.
+func (t *Task) Retries() uint64 {
+	return t.retries.Load()
+}
+
+// Metadata returns the raw container and task metadata responses last fetched by the task,
+// or nil if not yet fetched.
+func (t *Task) Metadata() (container, task []byte) {
+	t.raw.mu.Lock()
+	defer t.raw.mu.Unlock()
+
+	return t.raw.container, t.raw.task
+}
+
+// ResetMetadata clears the raw metadata responses, so Metadata only returns those fetched
+// afterwards.
+func (t *Task) ResetMetadata() {
+	t.raw.mu.Lock()
+	defer t.raw.mu.Unlock()
+
+	t.raw.container, t.raw.task = nil, nil
+}
+
+// rawMetadata holds the raw metadata responses last fetched.
+type rawMetadata struct {
+	mu        sync.Mutex
+	container []byte
+	task      []byte
+}
+
+func (r *rawMetadata) set(dst *[]byte, raw []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	*dst = raw
+}
+
+// Limits represents the CPU and memory limits of the container and the task,
+// along with the container labels and tags which may override how they are applied.
+type Limits struct {
+	// ContainerCPU is the container CPU limit in CPU units, where 1024 units is 1 vCPU.
+	ContainerCPU float64
+	// TaskCPU is the task CPU limit in vCPUs.
+	TaskCPU float64
+	// ContainerMemory is the container memory limit in MiB.
+	ContainerMemory float64
+	// TaskMemory is the task memory limit in MiB.
+	TaskMemory float64
+	// Identity identifies the task and the container.
+	Identity Identity
+	// Labels are the Docker labels of the container.
+	Labels map[string]string
+	// TaskTags are the tags of the task, only set when task tags are enabled.
+	TaskTags map[string]string
+	// ContainerInstanceTags are the tags of the EC2 container instance running the task,
+	// only set when task tags are enabled.
+	ContainerInstanceTags map[string]string
+	// TagsErr is the reason the tags are unavailable when task tags are enabled,
+	// in which case the limits are from the task metadata without tags, or wraps
+	// ErrTagsIncomplete when only some of the tags are unavailable.
+	TagsErr error
+	// MatchedBy is the identifier the container was matched by in the task metadata,
+	// or empty if the container was not found, in which case ContainerCPU is the limit
+	// in the container metadata, if any.
+	MatchedBy string
+	// Unusable describes the limit fields set in the metadata with a value which
+	// could not be used, e.g. `task Limits.CPU="two"`. These fields are treated as not set.
+	Unusable []string
+}
+
+// Identity identifies the task and the container.
+type Identity struct {
+	// Cluster is the name or ARN of the cluster running the task.
+	Cluster string
+	// TaskARN is the ARN of the task.
+	TaskARN string
+	// Family is the task definition family.
+	Family string
+	// Revision is the task definition revision.
+	Revision string
+	// ServiceName is the name of the service the task belongs to, if any. Only reported
+	// by recent versions of the ECS agent.
+	ServiceName string
+	// LaunchType is the launch type of the task, e.g. EC2 or FARGATE.
+	LaunchType string
+	// AvailabilityZone is the availability zone the task is running in.
+	AvailabilityZone string
+	// ContainerID is the Docker ID of the container.
+	ContainerID string
+	// ContainerName is the name of the container in the task definition.
+	ContainerName string
+	// ContainerARN is the ARN of the container.
+	ContainerARN string
+}
+
+// TaskID returns the ID of the task, the last segment of the task ARN.
+func (i Identity) TaskID() string {
+	return i.TaskARN[strings.LastIndex(i.TaskARN, "/")+1:]
+}
+
+// ClusterName returns the name of the cluster, which the task metadata may contain as
+// the cluster ARN.
+func (i Identity) ClusterName() string {
+	if !strings.HasPrefix(i.Cluster, arnPrefix) {
+		return i.Cluster
 	}
+
+	return i.Cluster[strings.LastIndex(i.Cluster, "/")+1:]
+}
+
+// ClusterARN returns the ARN of the cluster. The task metadata may contain the cluster
+// name rather than its ARN, in which case the ARN is derived from the task ARN.
+// An empty string is returned if neither is known.
+func (i Identity) ClusterARN() string {
+	if i.Cluster == "" || strings.HasPrefix(i.Cluster, arnPrefix) {
+		return i.Cluster
+	}
+
+	prefix, _, ok := strings.Cut(i.TaskARN, ":task/")
+	if !ok || !strings.HasPrefix(prefix, arnPrefix) {
+		return ""
+	}
+
+	return prefix + ":cluster/" + i.Cluster
+}
+
+// Stats represents the CPU throttling stats of the container.
+type Stats struct {
+	// Periods is the number of CFS enforcement periods elapsed.
+	Periods uint64
+	// ThrottledPeriods is the number of periods in which the container was throttled.
+	ThrottledPeriods uint64
+	// ThrottledTime is the total time the container was throttled.
+	ThrottledTime time.Duration
 }
 
 // GetMaxProcs is responsible for getting the max number of processors, or
@@ -61,39 +230,162 @@ func New(cfg config.Config) *Task {
 // If no CPU limit is found for the container, then the max number of threads
 // returned is the number of CPU's for the ECS Task.
 func (t *Task) GetMaxProcs(ctx context.Context) (int, error) {
-	container, err := t.getContainerMeta(ctx)
+	limits, err := t.GetLimits(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get ECS container meta: %w", err)
+		return 0, err
 	}
 
-	task, err := t.getTaskMeta(ctx)
+	return limits.MaxProcs(), nil
+}
+
+// GetLimits gets the CPU limits of the container and the task from the ECS metadata.
+// The container and task metadata are fetched concurrently.
+func (t *Task) GetLimits(ctx context.Context) (Limits, error) {
+	container, task, err := t.getContainerAndTaskMeta(ctx)
 	if err != nil {
-		return 0, fmt.Errorf("failed to get ECS task meta: %w", err)
+		return Limits{}, err
+	}
+
+	limits := Limits{
+		TaskCPU:         task.Limits.CPU.value,
+		ContainerMemory: container.Limits.Memory.value,
+		TaskMemory:      task.Limits.Memory.value,
+		Labels:          container.Labels,
+		Identity:        newIdentity(container, task),
+
+		TaskTags:              task.TaskTags,
+		ContainerInstanceTags: task.ContainerInstanceTags,
+		TagsErr:               task.tagsErr,
+	}
+
+	limits.Unusable = append(container.Limits.unusable("container"), task.Limits.unusable("task")...)
+
+	if taskContainer, matchedBy, ok := findContainer(container, task.Containers); ok {
+		limits.ContainerCPU = taskContainer.Limits.CPU.value
+		limits.MatchedBy = matchedBy
+		limits.Unusable = append(limits.Unusable, taskContainer.Limits.unusable("task container")...)
+	} else {
+		// Fall back to the limit in the container metadata, then to the task limit.
+		limits.ContainerCPU = container.Limits.CPU.value
 	}
 
 	// Either the container limit or the task limit must be set
-	if container.Limits.CPU == 0 && task.Limits.CPU == 0 {
-		return 0, errNoCPULimit
+	if container.Limits.CPU.value == 0 && task.Limits.CPU.value == 0 {
+		return limits, errNoCPULimit
+	}
+
+	return limits, nil
+}
+
+// GetIdentity gets the identity of the task and the container from the ECS metadata.
+func (t *Task) GetIdentity(ctx context.Context) (Identity, error) {
+	container, task, err := t.getContainerAndTaskMeta(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	return newIdentity(container, task), nil
+}
+
+// getContainerAndTaskMeta fetches the container and task metadata concurrently. The first
+// fetch to fail cancels the other, and its error is returned.
+func (t *Task) getContainerAndTaskMeta(ctx context.Context) (container, taskMeta, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg        sync.WaitGroup
+		once      sync.Once
+		container container
+		firstErr  error
+	)
+
+	fail := func(err error) {
+		once.Do(func() {
+			firstErr = err
+			cancel()
+		})
+	}
+
+	wg.Add(1)
+
+	go func() {
+		defer wg.Done()
+
+		var err error
+		if container, err = t.getContainerMeta(ctx); err != nil {
+			fail(fmt.Errorf("failed to get ECS container meta: %w", err))
+		}
+	}()
+
+	task, err := t.getTaskMetaWithTags(ctx)
+	if err != nil {
+		fail(fmt.Errorf("failed to get ECS task meta: %w", err))
 	}
 
-	var containerCPULimit float64
+	wg.Wait()
+
+	return container, task, firstErr
+}
 
-	for _, taskContainer := range task.Containers {
-		if container.DockerID == taskContainer.DockerID {
-			containerCPULimit = taskContainer.Limits.CPU
+func newIdentity(c container, task taskMeta) Identity {
+	return Identity{
+		Cluster:          task.Cluster,
+		TaskARN:          task.TaskARN,
+		Family:           task.Family,
+		Revision:         task.Revision,
+		ServiceName:      task.ServiceName,
+		LaunchType:       task.LaunchType,
+		AvailabilityZone: task.AvailabilityZone,
+		ContainerID:      c.DockerID,
+		ContainerName:    c.Name,
+		ContainerARN:     c.ContainerARN,
+	}
+}
+
+// GetStats gets the CPU throttling stats of the container from the ECS metadata.
+func (t *Task) GetStats(ctx context.Context) (Stats, error) {
+	stats, err := t.getContainerStats(ctx)
+	if err != nil {
+		return Stats{}, fmt.Errorf("failed to get ECS container stats: %w", err)
+	}
+
+	throttling := stats.CPUStats.ThrottlingData
+
+	return Stats{
+		Periods:          throttling.Periods,
+		ThrottledPeriods: throttling.ThrottledPeriods,
+		ThrottledTime:    time.Duration(throttling.ThrottledTime), //nolint:gosec // throttled time in ns fits int64.
+	}, nil
+}
+
+// findContainer finds the container in the task containers, preferring a match by
+// Docker ID over the container ARN and the name.
+func findContainer(c container, containers []container) (container, string, bool) {
+	for _, matchBy := range []string{MatchDockerID, MatchContainerARN, MatchName} {
+		for _, taskContainer := range containers {
+			if c.match(taskContainer) == matchBy {
+				return taskContainer, matchBy, true
+			}
 		}
 	}
 
-	if containerCPULimit == 0 {
-		return max(int(task.Limits.CPU), minCPU), nil
+	return container{}, "", false
+}
+
+// MaxProcs returns the max number of processors based on the limits.
+// See GetMaxProcs.
+func (l Limits) MaxProcs() int {
+	if l.ContainerCPU == 0 {
+		return max(int(l.TaskCPU), minCPU)
 	}
 
-	cpu := max(int(containerCPULimit)>>cpuUnits, minCPU)
+	cpu := max(int(l.ContainerCPU)>>cpuUnitsShift, minCPU)
 
-	taskCPULimit := int(task.Limits.CPU)
+	taskCPULimit := int(l.TaskCPU)
 	if taskCPULimit > 0 {
-		return min(taskCPULimit, cpu), nil
+		return min(taskCPULimit, cpu)
 	}
 
-	return cpu, nil
+	return cpu
 }
@@ -23,6 +23,7 @@ package task_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -238,6 +239,572 @@ func TestTask_GetMaxProcs_GetsCPUUsingTaskLimit(t *testing.T) {
 	}
 }
 
+func TestTask_GetLimits_GetsContainerAndTaskLimits(t *testing.T) {
+	t.Parallel()
+
+	containerCPU, taskCPU := 2<<10, 4
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start()
+	defer agent.Close()
+
+	ecsTask := task.New(config.Config{
+		ContainerMetadataURI: agent.GetContainerMetaEndpoint(),
+		TaskMetadataURI:      agent.GetTaskMetaEndpoint(),
+	})
+
+	limits, err := ecsTask.GetLimits(context.Background())
+	require.NoError(t, err)
+
+	want := task.Limits{
+		ContainerCPU: float64(containerCPU),
+		TaskCPU:      float64(taskCPU),
+		Identity:     task.Identity{ContainerID: "container-id"},
+		MatchedBy:    task.MatchDockerID,
+	}
+	assert.Equal(t, want, limits)
+	assert.Equal(t, 2, limits.MaxProcs())
+}
+
+func TestTask_GetLimits_DecodesLimitsLeniently(t *testing.T) {
+	t.Parallel()
+
+	containerIdentity := task.Identity{ContainerID: "container-id"}
+
+	tableTest := []struct {
+		name          string
+		containerMeta string
+		taskMeta      string
+		wantLimits    task.Limits
+		wantErr       bool
+	}{
+		{
+			name:          "should decode numeric limits",
+			containerMeta: `{"DockerId":"container-id","Limits":{"CPU":2048,"Memory":512}}`,
+			taskMeta:      `{"Containers":[{"DockerId":"container-id","Limits":{"CPU":2048}}],"Limits":{"CPU":4,"Memory":1024}}`,
+			wantLimits: task.Limits{
+				ContainerCPU:    2048,
+				TaskCPU:         4,
+				ContainerMemory: 512,
+				TaskMemory:      1024,
+				MatchedBy:       task.MatchDockerID,
+				Identity:        containerIdentity,
+			},
+		},
+		{
+			name:          "should decode numeric string limits",
+			containerMeta: `{"DockerId":"container-id","Limits":{"CPU":"2048","Memory":" 512 "}}`,
+			taskMeta: `{"Containers":[{"DockerId":"container-id","Limits":{"CPU":"2048"}}],` +
+				`"Limits":{"CPU":"0.5","Memory":"1024"}}`,
+			wantLimits: task.Limits{
+				ContainerCPU:    2048,
+				TaskCPU:         0.5,
+				ContainerMemory: 512,
+				TaskMemory:      1024,
+				MatchedBy:       task.MatchDockerID,
+				Identity:        containerIdentity,
+			},
+		},
+		{
+			name:          "should treat null and omitted limits as not set",
+			containerMeta: `{"DockerId":"container-id","Limits":{"CPU":null}}`,
+			taskMeta:      `{"Containers":[{"DockerId":"container-id","Limits":null}],"Limits":{"CPU":2,"Memory":""}}`,
+			wantLimits:    task.Limits{TaskCPU: 2, MatchedBy: task.MatchDockerID, Identity: containerIdentity},
+		},
+		{
+			name:          "should report unusable limits and resolve with the valid limits",
+			containerMeta: `{"DockerId":"container-id","Limits":{"CPU":"two","Memory":true}}`,
+			taskMeta:      `{"Containers":[{"DockerId":"container-id","Limits":{"CPU":{}}}],"Limits":{"CPU":2,"Memory":-1}}`,
+			wantLimits: task.Limits{
+				TaskCPU:   2,
+				MatchedBy: task.MatchDockerID,
+				Identity:  containerIdentity,
+				Unusable: []string{
+					`container Limits.CPU="two"`, "container Limits.Memory=true", "task Limits.Memory=-1",
+					"task container Limits.CPU={}",
+				},
+			},
+		},
+		{
+			name:          "should raise error when no usable CPU limit",
+			containerMeta: `{"DockerId":"container-id","Limits":{"CPU":"1024 units"}}`,
+			taskMeta:      `{"Containers":[],"Limits":{"CPU":"NaN"}}`,
+			wantLimits: task.Limits{
+				Identity: containerIdentity,
+				Unusable: []string{`container Limits.CPU="1024 units"`, `task Limits.CPU="NaN"`},
+			},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			agent := tasktest.NewECSAgent(t).
+				WithContainerMetaEndpointJSON(tt.containerMeta).
+				WithTaskMetaEndpointJSON(tt.taskMeta).
+				Start()
+			defer agent.Close()
+
+			ecsTask := task.New(config.Config{
+				ContainerMetadataURI: agent.GetContainerMetaEndpoint(),
+				TaskMetadataURI:      agent.GetTaskMetaEndpoint(),
+			})
+
+			limits, err := ecsTask.GetLimits(context.Background())
+			if tt.wantErr {
+				require.ErrorContains(t, err, "no CPU limit found for task or container")
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tt.wantLimits, limits)
+		})
+	}
+}
+
+func TestTask_GetLimits_MatchesContainerInTaskMeta(t *testing.T) {
+	t.Parallel()
+
+	tableTest := []struct {
+		name          string
+		containerMeta string
+		wantLimits    task.Limits
+	}{
+		{
+			name:          "should match container by Docker ID",
+			containerMeta: `{"DockerId":"docker-2","Name":"app","ContainerARN":"arn-1","Limits":{"CPU":2048}}`,
+			wantLimits: task.Limits{
+				ContainerCPU: 2048,
+				TaskCPU:      4,
+				MatchedBy:    task.MatchDockerID,
+				Identity:     task.Identity{ContainerID: "docker-2", ContainerName: "app", ContainerARN: "arn-1"},
+			},
+		},
+		{
+			name:          "should match container by container ARN when Docker ID does not match",
+			containerMeta: `{"DockerId":"docker-3","Name":"sidecar","ContainerARN":"arn-1","Limits":{"CPU":1024}}`,
+			wantLimits: task.Limits{
+				ContainerCPU: 1024,
+				TaskCPU:      4,
+				MatchedBy:    task.MatchContainerARN,
+				Identity:     task.Identity{ContainerID: "docker-3", ContainerName: "sidecar", ContainerARN: "arn-1"},
+			},
+		},
+		{
+			name:          "should match container by name when Docker ID and container ARN do not match",
+			containerMeta: `{"DockerId":"docker-3","Name":"sidecar","Limits":{"CPU":3072}}`,
+			wantLimits: task.Limits{
+				ContainerCPU: 2048,
+				TaskCPU:      4,
+				MatchedBy:    task.MatchName,
+				Identity:     task.Identity{ContainerID: "docker-3", ContainerName: "sidecar"},
+			},
+		},
+		{
+			name:          "should not match container when no identifier matches",
+			containerMeta: `{"DockerId":"docker-3","Name":"other","Limits":{"CPU":1024}}`,
+			wantLimits: task.Limits{
+				ContainerCPU: 1024,
+				TaskCPU:      4,
+				Identity:     task.Identity{ContainerID: "docker-3", ContainerName: "other"},
+			},
+		},
+		{
+			name:          "should not match container on empty identifiers",
+			containerMeta: `{"Limits":{"CPU":1024}}`,
+			wantLimits:    task.Limits{ContainerCPU: 1024, TaskCPU: 4},
+		},
+		{
+			name:          "should fall back to task limit when not matched and no container limit",
+			containerMeta: `{"DockerId":"docker-3","Name":"other"}`,
+			wantLimits: task.Limits{
+				TaskCPU:  4,
+				Identity: task.Identity{ContainerID: "docker-3", ContainerName: "other"},
+			},
+		},
+	}
+
+	taskMeta := `{"Containers":[` +
+		`{"DockerId":"docker-1","Name":"app","ContainerARN":"arn-1","Limits":{"CPU":1024}},` +
+		`{"DockerId":"docker-2","Name":"sidecar","Limits":{"CPU":2048}},` +
+		`{"Limits":{"CPU":512}}` +
+		`],"Limits":{"CPU":4}}`
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			agent := tasktest.NewECSAgent(t).
+				WithContainerMetaEndpointJSON(tt.containerMeta).
+				WithTaskMetaEndpointJSON(taskMeta).
+				Start()
+			defer agent.Close()
+
+			ecsTask := task.New(config.Config{
+				ContainerMetadataURI: agent.GetContainerMetaEndpoint(),
+				TaskMetadataURI:      agent.GetTaskMetaEndpoint(),
+			})
+
+			limits, err := ecsTask.GetLimits(context.Background())
+			require.NoError(t, err)
+
+			assert.Equal(t, tt.wantLimits, limits)
+		})
+	}
+}
+
+func TestTask_GetLimits_GetsTaskTags(t *testing.T) {
+	t.Parallel()
+
+	containerIdentity := task.Identity{ContainerID: "container-id"}
+
+	taskMeta := `{"Containers":[{"DockerId":"container-id","Limits":{"CPU":1024}}],"Limits":{"CPU":2}}`
+	taskWithTagsMeta := `{"Containers":[{"DockerId":"container-id","Limits":{"CPU":1024}}],"Limits":{"CPU":2},` +
+		`"TaskTags":{"gomaxecs.headroom":"0.5"},"ContainerInstanceTags":{"gomaxecs.procs":"1"}}`
+
+	tableTest := []struct {
+		name       string
+		taskTags   bool
+		agent      func(agent *tasktest.ECSAgent) *tasktest.ECSAgent
+		wantLimits task.Limits
+		wantErr    string
+	}{
+		{
+			name: "should not get tags when task tags disabled",
+			agent: func(agent *tasktest.ECSAgent) *tasktest.ECSAgent {
+				return agent.WithTaskWithTagsMetaEndpointJSON(taskWithTagsMeta)
+			},
+			wantLimits: task.Limits{
+				ContainerCPU: 1024,
+				TaskCPU:      2,
+				MatchedBy:    task.MatchDockerID,
+				Identity:     containerIdentity,
+			},
+		},
+		{
+			name:     "should get tags when task tags enabled",
+			taskTags: true,
+			agent: func(agent *tasktest.ECSAgent) *tasktest.ECSAgent {
+				return agent.WithTaskWithTagsMetaEndpointJSON(taskWithTagsMeta)
+			},
+			wantLimits: task.Limits{
+				ContainerCPU:          1024,
+				TaskCPU:               2,
+				MatchedBy:             task.MatchDockerID,
+				Identity:              containerIdentity,
+				TaskTags:              map[string]string{"gomaxecs.headroom": "0.5"},
+				ContainerInstanceTags: map[string]string{"gomaxecs.procs": "1"},
+			},
+		},
+		{
+			name:     "should fall back to task metadata when tags unavailable",
+			taskTags: true,
+			agent: func(agent *tasktest.ECSAgent) *tasktest.ECSAgent {
+				return agent.WithTaskWithTagsMetaEndpointNotFound()
+			},
+			wantLimits: task.Limits{
+				ContainerCPU: 1024,
+				TaskCPU:      2,
+				MatchedBy:    task.MatchDockerID,
+				Identity:     containerIdentity,
+			},
+			wantErr: "task tags unavailable: request failed, status code: 404",
+		},
+		{
+			name:     "should report tags the agent failed to get",
+			taskTags: true,
+			agent: func(agent *tasktest.ECSAgent) *tasktest.ECSAgent {
+				return agent.WithTaskWithTagsMetaEndpointJSON(
+					`{"Containers":[{"DockerId":"container-id","Limits":{"CPU":1024}}],"Limits":{"CPU":2},` +
+						`"TaskTags":{"gomaxecs.headroom":"0.5"},"Errors":[{"ErrorField":"ContainerInstanceTags",` +
+						`"ErrorCode":"AccessDeniedException","ErrorMessage":"not authorized","StatusCode":400}]}`)
+			},
+			wantLimits: task.Limits{
+				ContainerCPU: 1024,
+				TaskCPU:      2,
+				MatchedBy:    task.MatchDockerID,
+				Identity:     containerIdentity,
+				TaskTags:     map[string]string{"gomaxecs.headroom": "0.5"},
+			},
+			wantErr: "task tags incomplete: ContainerInstanceTags: AccessDeniedException: not authorized",
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			agent := tt.agent(tasktest.NewECSAgent(t).
+				WithContainerMetaEndpoint(1024).
+				WithTaskMetaEndpointJSON(taskMeta)).
+				Start()
+			defer agent.Close()
+
+			ecsTask := task.New(config.Config{
+				ContainerMetadataURI:    agent.GetContainerMetaEndpoint(),
+				TaskMetadataURI:         agent.GetTaskMetaEndpoint(),
+				TaskWithTagsMetadataURI: agent.GetTaskWithTagsMetaEndpoint(),
+				TaskTags:                tt.taskTags,
+			})
+
+			limits, err := ecsTask.GetLimits(context.Background())
+			require.NoError(t, err)
+
+			if tt.wantErr != "" {
+				require.EqualError(t, limits.TagsErr, tt.wantErr)
+				limits.TagsErr = nil
+			}
+
+			assert.Equal(t, tt.wantLimits, limits)
+		})
+	}
+}
+
+func TestTask_GetLimits_GetsIdentity(t *testing.T) {
+	t.Parallel()
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(identityContainerMeta).
+		WithTaskMetaEndpointJSON(identityTaskMeta).
+		Start()
+	defer agent.Close()
+
+	ecsTask := task.New(config.Config{
+		ContainerMetadataURI: agent.GetContainerMetaEndpoint(),
+		TaskMetadataURI:      agent.GetTaskMetaEndpoint(),
+	})
+
+	limits, err := ecsTask.GetLimits(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, wantIdentity(), limits.Identity)
+}
+
+func TestTask_GetIdentity(t *testing.T) {
+	t.Parallel()
+
+	tableTest := []struct {
+		name         string
+		taskMeta     string
+		wantIdentity task.Identity
+		wantErr      string
+	}{
+		{
+			name:         "should get identity of task and container",
+			taskMeta:     identityTaskMeta,
+			wantIdentity: wantIdentity(),
+		},
+		{
+			name:     "should get identity when task has no CPU limit",
+			taskMeta: `{"TaskARN":"arn:aws:ecs:us-east-1:123456789012:task/default/abc","Containers":[]}`,
+			wantIdentity: task.Identity{
+				TaskARN:       "arn:aws:ecs:us-east-1:123456789012:task/default/abc",
+				ContainerID:   "container-id",
+				ContainerName: "app",
+				ContainerARN:  "arn:aws:ecs:us-east-1:123456789012:container/default/abc/def",
+			},
+		},
+		{
+			name:     "should raise error when task metadata invalid",
+			taskMeta: `{"Family":`,
+			wantErr:  "failed to get ECS task meta: unmarshal failed",
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			agent := tasktest.NewECSAgent(t).
+				WithContainerMetaEndpointJSON(identityContainerMeta).
+				WithTaskMetaEndpointJSON(tt.taskMeta).
+				Start()
+			defer agent.Close()
+
+			ecsTask := task.New(config.Config{
+				ContainerMetadataURI: agent.GetContainerMetaEndpoint(),
+				TaskMetadataURI:      agent.GetTaskMetaEndpoint(),
+			})
+
+			identity, err := ecsTask.GetIdentity(context.Background())
+			if tt.wantErr != "" {
+				require.ErrorContains(t, err, tt.wantErr)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantIdentity, identity)
+		})
+	}
+}
+
+const (
+	identityContainerMeta = `{"DockerId":"container-id","Name":"app",` +
+		`"ContainerARN":"arn:aws:ecs:us-east-1:123456789012:container/default/abc/def","Limits":{"CPU":1024}}`
+	identityTaskMeta = `{"Cluster":"default","TaskARN":"arn:aws:ecs:us-east-1:123456789012:task/default/abc",` +
+		`"Family":"service","Revision":"7","ServiceName":"api","LaunchType":"FARGATE","AvailabilityZone":"us-east-1a",` +
+		`"Containers":[{"DockerId":"container-id"}],"Limits":{"CPU":2}}`
+)
+
+func wantIdentity() task.Identity {
+	return task.Identity{
+		Cluster:          "default",
+		TaskARN:          "arn:aws:ecs:us-east-1:123456789012:task/default/abc",
+		Family:           "service",
+		Revision:         "7",
+		ServiceName:      "api",
+		LaunchType:       "FARGATE",
+		AvailabilityZone: "us-east-1a",
+		ContainerID:      "container-id",
+		ContainerName:    "app",
+		ContainerARN:     "arn:aws:ecs:us-east-1:123456789012:container/default/abc/def",
+	}
+}
+
+func TestTask_Metadata_GetsRawMetadata(t *testing.T) {
+	t.Parallel()
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(identityContainerMeta).
+		WithTaskMetaEndpointJSON(identityTaskMeta).
+		Start()
+	defer agent.Close()
+
+	ecsTask := task.New(config.Config{
+		ContainerMetadataURI: agent.GetContainerMetaEndpoint(),
+		TaskMetadataURI:      agent.GetTaskMetaEndpoint(),
+	})
+
+	container, taskMeta := ecsTask.Metadata()
+	assert.Nil(t, container)
+	assert.Nil(t, taskMeta)
+
+	_, err := ecsTask.GetLimits(context.Background())
+	require.NoError(t, err)
+
+	container, taskMeta = ecsTask.Metadata()
+	assert.JSONEq(t, identityContainerMeta, string(container))
+	assert.JSONEq(t, identityTaskMeta, string(taskMeta))
+}
+
+func TestIdentity_TaskID(t *testing.T) {
+	t.Parallel()
+
+	tableTest := []struct {
+		name    string
+		taskARN string
+		want    string
+	}{
+		{name: "should get ID from task ARN", taskARN: "arn:aws:ecs:us-east-1:123456789012:task/default/abc", want: "abc"},
+		{name: "should get ID from legacy task ARN", taskARN: "arn:aws:ecs:us-east-1:123456789012:task/abc", want: "abc"},
+		{name: "should get no ID when no task ARN", taskARN: "", want: ""},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, task.Identity{TaskARN: tt.taskARN}.TaskID())
+		})
+	}
+}
+
+func TestIdentity_ClusterName(t *testing.T) {
+	t.Parallel()
+
+	tableTest := []struct {
+		name    string
+		cluster string
+		want    string
+	}{
+		{
+			name:    "should get name from cluster ARN",
+			cluster: "arn:aws:ecs:us-east-1:123456789012:cluster/default",
+			want:    "default",
+		},
+		{name: "should get cluster name as is", cluster: "default", want: "default"},
+		{name: "should get no name when no cluster", cluster: "", want: ""},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, task.Identity{Cluster: tt.cluster}.ClusterName())
+		})
+	}
+}
+
+func TestIdentity_ClusterARN(t *testing.T) {
+	t.Parallel()
+
+	taskARN := "arn:aws:ecs:us-east-1:123456789012:task/default/abc"
+
+	tableTest := []struct {
+		name     string
+		identity task.Identity
+		want     string
+	}{
+		{
+			name:     "should get cluster ARN as is",
+			identity: task.Identity{Cluster: "arn:aws:ecs:us-east-1:123456789012:cluster/default", TaskARN: taskARN},
+			want:     "arn:aws:ecs:us-east-1:123456789012:cluster/default",
+		},
+		{
+			name:     "should derive cluster ARN from task ARN when cluster is a name",
+			identity: task.Identity{Cluster: "default", TaskARN: taskARN},
+			want:     "arn:aws:ecs:us-east-1:123456789012:cluster/default",
+		},
+		{
+			name:     "should get no cluster ARN when task ARN is invalid",
+			identity: task.Identity{Cluster: "default", TaskARN: "abc"},
+			want:     "",
+		},
+		{
+			name:     "should get no cluster ARN when no cluster",
+			identity: task.Identity{TaskARN: taskARN},
+			want:     "",
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, tt.identity.ClusterARN())
+		})
+	}
+}
+
+func TestTask_GetStats_GetsThrottlingStats(t *testing.T) {
+	t.Parallel()
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerStatsEndpoint(100, 25, int(time.Second)).
+		Start()
+	defer agent.Close()
+
+	ecsTask := task.New(config.Config{ContainerStatsURI: agent.GetContainerStatsEndpoint()})
+
+	stats, err := ecsTask.GetStats(context.Background())
+	require.NoError(t, err)
+
+	assert.Equal(t, task.Stats{Periods: 100, ThrottledPeriods: 25, ThrottledTime: time.Second}, stats)
+}
+
+func TestTask_GetStats_ReturnsErrorWhenFailToGetStats(t *testing.T) {
+	t.Parallel()
+
+	ecsTask := task.New(config.Config{ContainerStatsURI: "invalid-uri"})
+
+	_, err := ecsTask.GetStats(context.Background())
+	assert.ErrorContains(t, err, "failed to get ECS container stats: request failed")
+}
+
 func TestTask_GetMaxProcs_ReturnsErrorWhenFailToGetNumCPU(t *testing.T) {
 	t.Parallel()
 
@@ -315,6 +882,22 @@ func TestTask_GetMaxProcs_ReturnsErrorWhenFailToGetNumCPU(t *testing.T) {
 				return agent.GetContainerMetaEndpoint(), agent.GetTaskMetaEndpoint()
 			},
 		},
+		{
+			name:      "should raise error when data follows the ECS task meta",
+			wantError: "failed to get ECS task meta: unmarshal failed: unexpected data after top-level value",
+			testServer: func(t *testing.T) (string, string) {
+				t.Helper()
+
+				agent := tasktest.NewECSAgent(t).
+					WithContainerMetaEndpoint(1 << 10).
+					WithTaskMetaEndpointJSON(`{"Containers":[],"Limits":{"CPU":1}}}`).
+					Start()
+
+				t.Cleanup(agent.Close)
+
+				return agent.GetContainerMetaEndpoint(), agent.GetTaskMetaEndpoint()
+			},
+		},
 		{
 			name:      "should raise error when fail to unmarshal ECS task meta",
 			wantError: "failed to get ECS task meta: unmarshal failed",
@@ -332,6 +915,23 @@ func TestTask_GetMaxProcs_ReturnsErrorWhenFailToGetNumCPU(t *testing.T) {
 				return agent.GetContainerMetaEndpoint(), agent.GetTaskMetaEndpoint()
 			},
 		},
+		{
+			name:      "should raise error when ECS task meta exceeds max response size",
+			wantError: "failed to get ECS task meta: metadata response too large: exceeds max size of 1048576 bytes",
+			testServer: func(t *testing.T) (string, string) {
+				t.Helper()
+
+				containerCPU, taskCPU := 1<<10, 1
+				agent := tasktest.NewECSAgent(t).
+					WithContainerMetaEndpoint(containerCPU).
+					WithTaskMetaEndpointOversized(1<<15, containerCPU, taskCPU).
+					Start()
+
+				t.Cleanup(agent.Close)
+
+				return agent.GetContainerMetaEndpoint(), agent.GetTaskMetaEndpoint()
+			},
+		},
 		{
 			name:      "should raise error when fail to get ECS container meta",
 			wantError: "failed to get ECS container meta: request failed",
@@ -379,10 +979,203 @@ func TestTask_GetMaxProcs_ReturnsErrorWhenFailToGetNumCPU(t *testing.T) {
 			t.Parallel()
 
 			containerMetaURI, taskMetaURI := tt.testServer(t)
-			ecsTask := task.New(config.Config{ContainerMetadataURI: containerMetaURI, TaskMetadataURI: taskMetaURI})
+			ecsTask := task.New(config.New(func(cfg *config.Config) {
+				cfg.ContainerMetadataURI = containerMetaURI
+				cfg.TaskMetadataURI = taskMetaURI
+			}))
 
 			_, err := ecsTask.GetMaxProcs(context.Background())
 			assert.ErrorContains(t, err, tt.wantError)
 		})
 	}
 }
+
+func TestTask_GetMaxProcs_RetriesFailedRequests(t *testing.T) {
+	t.Parallel()
+
+	tableTest := []struct {
+		name        string
+		failures    int
+		maxRetries  int
+		wantRetries uint64
+		wantError   string
+	}{
+		{
+			name:        "should get cpu when task endpoint recovers within max retries",
+			failures:    2,
+			maxRetries:  2,
+			wantRetries: 2,
+		},
+		{
+			name:        "should raise error when task endpoint does not recover within max retries",
+			failures:    3,
+			maxRetries:  2,
+			wantRetries: 2,
+			wantError:   "failed to get ECS task meta: request failed, status code: 503",
+		},
+		{
+			name:       "should raise error when retries are disabled",
+			failures:   1,
+			maxRetries: 0,
+			wantError:  "failed to get ECS task meta: request failed, status code: 503",
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			containerCPU, taskCPU := 2<<10, 4
+
+			agent := tasktest.NewECSAgent(t).
+				WithContainerMetaEndpoint(containerCPU).
+				WithTaskMetaEndpointUnavailable(tt.failures, containerCPU, taskCPU).
+				Start()
+			defer agent.Close()
+
+			ecsTask := task.New(config.Config{
+				ContainerMetadataURI: agent.GetContainerMetaEndpoint(),
+				TaskMetadataURI:      agent.GetTaskMetaEndpoint(),
+				Retry:                config.Retry{MaxRetries: tt.maxRetries, Backoff: time.Millisecond},
+			})
+
+			gotCPU, err := ecsTask.GetMaxProcs(context.Background())
+			assert.Equal(t, tt.wantRetries, ecsTask.Retries())
+
+			if tt.wantError != "" {
+				assert.ErrorContains(t, err, tt.wantError)
+				return
+			}
+
+			require.NoError(t, err)
+			assert.Equal(t, 2, gotCPU)
+		})
+	}
+}
+
+func TestTask_GetLimits_CancelsTaskMetaWhenContainerMetaFails(t *testing.T) {
+	t.Parallel()
+
+	agent := tasktest.NewECSAgent(t).
+		WithTaskMetaEndpoint(2<<10, 4).
+		WithLatency(5 * time.Second).
+		Start()
+	defer agent.Close()
+
+	ecsTask := task.New(config.Config{
+		ContainerMetadataURI: "invalid-uri",
+		TaskMetadataURI:      agent.GetTaskMetaEndpoint(),
+	})
+
+	start := time.Now()
+	_, err := ecsTask.GetLimits(context.Background())
+
+	require.ErrorContains(t, err, "failed to get ECS container meta")
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestTask_GetMaxProcs_HonorsContextCancellation(t *testing.T) {
+	t.Parallel()
+
+	tableTest := []struct {
+		name    string
+		latency time.Duration
+		backoff time.Duration
+	}{
+		{
+			name:    "should return context error when request exceeds context deadline",
+			latency: time.Second,
+			backoff: time.Millisecond,
+		},
+		{
+			name:    "should return context error when retry backoff exceeds context deadline",
+			latency: 0,
+			backoff: time.Second,
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			agent := tasktest.NewECSAgent(t).
+				WithContainerMetaEndpointInternalServerError().
+				WithLatency(tt.latency).
+				Start()
+			defer agent.Close()
+
+			ecsTask := task.New(config.Config{
+				ContainerMetadataURI: agent.GetContainerMetaEndpoint(),
+				TaskMetadataURI:      agent.GetTaskMetaEndpoint(),
+				Retry:                config.Retry{MaxRetries: 5, Backoff: tt.backoff},
+			})
+
+			ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+			defer cancel()
+
+			start := time.Now()
+
+			_, err := ecsTask.GetMaxProcs(ctx)
+			require.ErrorIs(t, err, context.DeadlineExceeded)
+			assert.Less(t, time.Since(start), tt.latency+tt.backoff)
+		})
+	}
+}
+
+// BenchmarkTask_GetMaxProcs measures the time taken to get the max procs. As the
+// container and task metadata are fetched concurrently, the time per operation
+// is bound by the latency of a single metadata request rather than two, see
+// BenchmarkTask_GetMetaSequentially.
+func BenchmarkTask_GetMaxProcs(b *testing.B) {
+	for _, latency := range []time.Duration{0, time.Millisecond, 5 * time.Millisecond} {
+		b.Run("latency="+latency.String(), func(b *testing.B) {
+			agent := tasktest.NewECSAgent(b).
+				WithContainerMetaEndpoint(2<<10).
+				WithTaskMetaEndpoint(2<<10, 4).
+				WithLatency(latency).
+				Start()
+			defer agent.Close()
+
+			ecsTask := task.New(config.New(func(cfg *config.Config) {
+				cfg.ContainerMetadataURI = agent.GetContainerMetaEndpoint()
+				cfg.TaskMetadataURI = agent.GetTaskMetaEndpoint()
+			}))
+
+			b.ResetTimer()
+
+			for range b.N {
+				if _, err := ecsTask.GetMaxProcs(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkTask_GetMetaSequentially measures the time taken to fetch the container and
+// task metadata one after the other, the baseline for BenchmarkTask_GetMaxProcs.
+func BenchmarkTask_GetMetaSequentially(b *testing.B) {
+	for _, latency := range []time.Duration{0, time.Millisecond, 5 * time.Millisecond} {
+		b.Run("latency="+latency.String(), func(b *testing.B) {
+			agent := tasktest.NewECSAgent(b).
+				WithContainerMetaEndpoint(2<<10).
+				WithTaskMetaEndpoint(2<<10, 4).
+				WithLatency(latency).
+				Start()
+			defer agent.Close()
+
+			ecsTask := task.New(config.New(func(cfg *config.Config) {
+				cfg.ContainerMetadataURI = agent.GetContainerMetaEndpoint()
+				cfg.TaskMetadataURI = agent.GetTaskMetaEndpoint()
+			}))
+
+			b.ResetTimer()
+
+			for range b.N {
+				if err := ecsTask.GetMetaSequentially(context.Background()); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
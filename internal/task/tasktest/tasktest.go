@@ -4,31 +4,37 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 )
 
 const (
-	metaURIEnv   = "ECS_CONTAINER_METADATA_URI_V4"
-	taskMetaPath = "/task"
+	metaURIEnv           = "ECS_CONTAINER_METADATA_URI_V4"
+	taskMetaPath         = "/task"
+	taskWithTagsMetaPath = "/taskWithTags"
+	statsPath            = "/stats"
 )
 
 // ECSAgent is a test server that simulates the ECS Agent metadata API.
 type ECSAgent struct {
-	t      *testing.T
-	mux    *http.ServeMux
-	server *httptest.Server
+	t       testing.TB
+	mux     *http.ServeMux
+	server  *httptest.Server
+	latency time.Duration
 }
 
 // NewECSAgent builds a new test server that simulates the ECS Agent metadata API.
 // https://docs.aws.amazon.com/AmazonECS/latest/developerguide/task-metadata-endpoint-v4.html
-func NewECSAgent(t *testing.T) *ECSAgent {
+func NewECSAgent(t testing.TB) *ECSAgent {
 	t.Helper()
 
 	mux := http.NewServeMux()
 
-	return &ECSAgent{t, mux, nil}
+	return &ECSAgent{t, mux, nil, 0}
 }
 
 // WithContainerMetaEndpoint sets up the container metadata endpoint on the test server.
@@ -59,6 +65,38 @@ func (e *ECSAgent) WithTaskMetaEndpoint(containerCPU, taskCPU int) *ECSAgent {
 	return e
 }
 
+// WithTaskMetaEndpointUnavailable sets up the task metadata endpoint to return a service unavailable
+// error for the first number of failures, after which it responds as WithTaskMetaEndpoint.
+func (e *ECSAgent) WithTaskMetaEndpointUnavailable(failures, containerCPU, taskCPU int) *ECSAgent {
+	e.t.Helper()
+
+	var requests atomic.Int32
+
+	e.mux.HandleFunc(taskMetaPath, func(w http.ResponseWriter, _ *http.Request) {
+		if int(requests.Add(1)) <= failures {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		_, err := w.Write([]byte(fmt.Sprintf(
+			`{"Containers":[{"DockerId":"container-id","Limits":{"CPU":%d}}],"Limits":{"CPU":%d}}`,
+			containerCPU,
+			taskCPU,
+		)))
+		assert.NoError(e.t, err)
+	})
+
+	return e
+}
+
+// WithLatency delays every response of the test server by latency, or until the request is canceled.
+func (e *ECSAgent) WithLatency(latency time.Duration) *ECSAgent {
+	e.t.Helper()
+	e.latency = latency
+
+	return e
+}
+
 // WithContainerMetaEndpointInternalServerError sets up the container metadata endpoint
 // to return an internal server error.
 func (e *ECSAgent) WithContainerMetaEndpointInternalServerError() *ECSAgent {
@@ -98,6 +136,80 @@ func (e *ECSAgent) WithTaskMetaEndpointInvalidJSON() *ECSAgent {
 	return e
 }
 
+// WithContainerMetaEndpointJSON sets up the container metadata endpoint to return the given JSON body.
+func (e *ECSAgent) WithContainerMetaEndpointJSON(body string) *ECSAgent {
+	e.t.Helper()
+	e.mux.HandleFunc("/", e.jsonHandler(body))
+
+	return e
+}
+
+// WithTaskMetaEndpointJSON sets up the task metadata endpoint to return the given JSON body.
+func (e *ECSAgent) WithTaskMetaEndpointJSON(body string) *ECSAgent {
+	e.t.Helper()
+	e.mux.HandleFunc(taskMetaPath, e.jsonHandler(body))
+
+	return e
+}
+
+// WithTaskWithTagsMetaEndpointJSON sets up the task metadata with tags endpoint to return the given JSON body.
+func (e *ECSAgent) WithTaskWithTagsMetaEndpointJSON(body string) *ECSAgent {
+	e.t.Helper()
+	e.mux.HandleFunc(taskWithTagsMetaPath, e.jsonHandler(body))
+
+	return e
+}
+
+// WithTaskWithTagsMetaEndpointNotFound sets up the task metadata with tags endpoint to return
+// not found, as when the ECS agent does not have tag support enabled.
+func (e *ECSAgent) WithTaskWithTagsMetaEndpointNotFound() *ECSAgent {
+	e.t.Helper()
+	e.mux.HandleFunc(taskWithTagsMetaPath, http.NotFound)
+
+	return e
+}
+
+// WithContainerStatsEndpoint sets up the container stats endpoint on the test server.
+func (e *ECSAgent) WithContainerStatsEndpoint(periods, throttledPeriods, throttledTime int) *ECSAgent {
+	e.t.Helper()
+
+	e.mux.HandleFunc(statsPath, e.jsonHandler(fmt.Sprintf(
+		`{"cpu_stats":{"throttling_data":{"periods":%d,"throttled_periods":%d,"throttled_time":%d}}}`,
+		periods,
+		throttledPeriods,
+		throttledTime,
+	)))
+
+	return e
+}
+
+func (e *ECSAgent) jsonHandler(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, _ *http.Request) {
+		_, err := w.Write([]byte(body))
+		assert.NoError(e.t, err)
+	}
+}
+
+// WithTaskMetaEndpointOversized sets up the task metadata endpoint to return valid JSON
+// padded with the given number of containers, to exceed the max response size.
+func (e *ECSAgent) WithTaskMetaEndpointOversized(containers, containerCPU, taskCPU int) *ECSAgent {
+	e.t.Helper()
+
+	e.mux.HandleFunc(taskMetaPath, func(w http.ResponseWriter, _ *http.Request) {
+		padding := strings.Repeat(`{"DockerId":"padding","Limits":{"CPU":0}},`, containers)
+
+		_, err := w.Write([]byte(fmt.Sprintf(
+			`{"Containers":[%s{"DockerId":"container-id","Limits":{"CPU":%d}}],"Limits":{"CPU":%d}}`,
+			padding,
+			containerCPU,
+			taskCPU,
+		)))
+		assert.NoError(e.t, err)
+	})
+
+	return e
+}
+
 func (e *ECSAgent) invalidJSONHandler(w http.ResponseWriter, _ *http.Request) {
 	_, err := w.Write([]byte("invlaid-json"))
 	assert.NoError(e.t, err)
@@ -106,11 +218,25 @@ func (e *ECSAgent) invalidJSONHandler(w http.ResponseWriter, _ *http.Request) {
 // Start starts the test server.
 func (e *ECSAgent) Start() *ECSAgent {
 	e.t.Helper()
-	e.server = httptest.NewServer(e.mux)
+	e.server = httptest.NewServer(e.delay(e.mux))
 
 	return e
 }
 
+func (e *ECSAgent) delay(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if e.latency > 0 {
+			select {
+			case <-time.After(e.latency):
+			case <-r.Context().Done():
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 // SetMetaURIEnv is a helper function to set the server url for ECS_CONTAINER_METADATA_URI_V4 environment variable.
 // This is useful for testing the ECS metadata API.
 func (e *ECSAgent) SetMetaURIEnv() *ECSAgent {
@@ -139,3 +265,15 @@ func (e *ECSAgent) GetTaskMetaEndpoint() string {
 	e.t.Helper()
 	return e.server.URL + taskMetaPath
 }
+
+// GetTaskWithTagsMetaEndpoint returns the task metadata with tags endpoint.
+func (e *ECSAgent) GetTaskWithTagsMetaEndpoint() string {
+	e.t.Helper()
+	return e.server.URL + taskWithTagsMetaPath
+}
+
+// GetContainerStatsEndpoint returns the container stats endpoint.
+func (e *ECSAgent) GetContainerStatsEndpoint() string {
+	e.t.Helper()
+	return e.server.URL + statsPath
+}
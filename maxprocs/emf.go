@@ -0,0 +1,57 @@
+package maxprocs
+
+import (
+	"log/slog"
+	"strconv"
+	"time"
+
+	"github.com/rdforte/gomaxecs/internal/emf"
+)
+
+const emfNamespace = "gomaxecs"
+
+// writeEMF writes the result as an EMF document, if enabled, so the ECS limits and
+// GOMAXPROCS of each task are available as CloudWatch metrics, with the dimensions
+// and properties from the identity.
+func (h *Handle) writeEMF(id Identity) {
+	if h.cfg.EMF == nil {
+		return
+	}
+
+	res := h.result
+	metrics := []emf.Metric{{Name: "GOMAXPROCS", Unit: emf.UnitCount, Value: float64(res.Procs)}}
+
+	for _, metric := range []emf.Metric{
+		{Name: "ContainerCPU", Unit: emf.UnitNone, Value: res.ContainerVCPUs()},
+		{Name: "TaskCPU", Unit: emf.UnitNone, Value: res.TaskCPU},
+		{Name: "ContainerMemory", Unit: emf.UnitMegabytes, Value: res.ContainerMemory},
+		{Name: "TaskMemory", Unit: emf.UnitMegabytes, Value: res.TaskMemory},
+	} {
+		if metric.Value > 0 {
+			metrics = append(metrics, metric)
+		}
+	}
+
+	doc := emf.Document{
+		Namespace: emfNamespace,
+		Timestamp: time.Now(),
+		Dimensions: []emf.Dimension{
+			{Name: "ClusterName", Value: id.ClusterName()},
+			{Name: "ServiceName", Value: id.ServiceName},
+			{Name: "TaskFamily", Value: id.Family},
+		},
+		Metrics: metrics,
+		Properties: map[string]string{
+			"Source":        string(res.Source),
+			"DryRun":        strconv.FormatBool(res.DryRun),
+			"TaskARN":       id.TaskARN,
+			"ContainerName": id.ContainerName,
+		},
+	}
+
+	if err := emf.Write(h.cfg.EMF, doc); err != nil {
+		logEvent(h.cfg, slog.LevelWarn, "maxprocs: Failed to write EMF document",
+			[]slog.Attr{slog.Any(keyError, err)},
+			"maxprocs: Failed to write EMF document: %v", err)
+	}
+}
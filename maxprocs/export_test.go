@@ -0,0 +1,9 @@
+package maxprocs
+
+// ResetTaskMetadata clears the cached task identity.
+func ResetTaskMetadata() {
+	metadata.mu.Lock()
+	defer metadata.mu.Unlock()
+
+	metadata.identity, metadata.ok = Identity{}, false
+}
@@ -0,0 +1,694 @@
+package maxprocs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/rdforte/gomaxecs/internal/client"
+	"github.com/rdforte/gomaxecs/internal/config"
+	ecstask "github.com/rdforte/gomaxecs/internal/task"
+)
+
+// Source describes where the value of GOMAXPROCS came from.
+type Source string
+
+const (
+	// SourceECS indicates GOMAXPROCS was set based on the ECS metadata.
+	SourceECS Source = "ecs"
+	// SourceEnv indicates GOMAXPROCS was left as set in the GOMAXPROCS environment variable.
+	SourceEnv Source = "env"
+	// SourceRuntime indicates GOMAXPROCS was left to the Go runtime.
+	SourceRuntime Source = "runtime"
+	// SourceOverride indicates GOMAXPROCS was set to a fixed value overridden in the container
+	// labels or tags.
+	SourceOverride Source = "override"
+	// SourceNone indicates GOMAXPROCS was not changed as it could not be resolved or is disabled.
+	SourceNone Source = "none"
+	// SourceAdmin indicates GOMAXPROCS was overridden at runtime, see Handle.Override.
+	SourceAdmin Source = "admin"
+)
+
+// changed returns true if GOMAXPROCS was changed from the source.
+func (s Source) changed() bool {
+	return s == SourceECS || s == SourceOverride || s == SourceAdmin
+}
+
+// Identity identifies the ECS task and container.
+type Identity = ecstask.Identity
+
+// Result describes the outcome of setting GOMAXPROCS.
+type Result struct {
+	// Procs is the value of GOMAXPROCS once set.
+	Procs int
+	// Previous is the value of GOMAXPROCS prior to being set.
+	Previous int
+	// Source is where the value of GOMAXPROCS came from.
+	Source Source
+	// SetBy is the function which set GOMAXPROCS.
+	SetBy string
+	// ContainerCPU is the container CPU limit in CPU units, where 1024 units is 1 vCPU.
+	ContainerCPU float64
+	// TaskCPU is the task CPU limit in vCPUs.
+	TaskCPU float64
+	// ContainerMemory is the container memory limit in MiB.
+	ContainerMemory float64
+	// TaskMemory is the task memory limit in MiB.
+	TaskMemory float64
+	// Identity identifies the ECS task and container.
+	Identity Identity
+	// Duration is the time taken to resolve GOMAXPROCS.
+	Duration time.Duration
+	// MemoryLimit is the Go memory limit in bytes once set, or 0 if it was not changed.
+	MemoryLimit int64
+	// PreviousMemoryLimit is the Go memory limit in bytes prior to being set.
+	PreviousMemoryLimit int64
+	// DryRun is true when GOMAXPROCS and the memory limit were resolved but not changed,
+	// in which case Procs, Source and MemoryLimit are what they would have been set to.
+	DryRun bool
+}
+
+// cpuUnitsPerVCPU is the number of ECS CPU units in 1 vCPU.
+const cpuUnitsPerVCPU = 1024
+
+// ContainerVCPUs returns the container CPU limit in vCPUs, or 0 if there is none.
+func (r Result) ContainerVCPUs() float64 {
+	return r.ContainerCPU / cpuUnitsPerVCPU
+}
+
+// ContainerMemoryBytes returns the container memory limit in bytes, or 0 if there is none.
+func (r Result) ContainerMemoryBytes() int64 {
+	return int64(r.ContainerMemory * bytesPerMiB)
+}
+
+// TaskMemoryBytes returns the task memory limit in bytes, or 0 if there is none.
+func (r Result) TaskMemoryBytes() int64 {
+	return int64(r.TaskMemory * bytesPerMiB)
+}
+
+var errHandleReset = errors.New("handle has been reset")
+
+// ErrCanceled is returned when the context is canceled or its deadline is exceeded
+// before GOMAXPROCS could be resolved. The context error is also wrapped.
+var ErrCanceled = errors.New("setting GOMAXPROCS canceled")
+
+// ErrResponseTooLarge is wrapped by the error when a metadata response is larger than the
+// max response size, see WithMaxResponseSize.
+//
+//nolint:gochecknoglobals // re-exported sentinel error.
+var ErrResponseTooLarge = client.ErrResponseTooLarge
+
+// ErrMalformedMetadata is wrapped by the error when a metadata response is not valid JSON,
+// or has data following the JSON value.
+//
+//nolint:gochecknoglobals // re-exported sentinel error.
+var ErrMalformedMetadata = ecstask.ErrMalformedMetadata
+
+// ErrStrict is the panic value, wrapping the error, when GOMAXPROCS could not be resolved in strict mode.
+var ErrStrict = errors.New("gomaxecs: strict mode, ECS detected but GOMAXPROCS could not be resolved")
+
+// state holds the handle of the active Set, the resolution in flight and the stats.
+// Guarding it with a single mutex ensures Set, Reset and Refresh are never interleaved
+// when applying GOMAXPROCS. The mutex is not held while resolving GOMAXPROCS from the
+// ECS metadata, so reading the state never waits on the metadata requests.
+//
+//nolint:gochecknoglobals // GOMAXPROCS is process wide state.
+var state struct {
+	mu       sync.Mutex
+	handle   *Handle
+	inflight *call
+	stats    Stats
+	last     attempt
+}
+
+// call is the first Set in flight, shared by concurrent calls so GOMAXPROCS is only
+// resolved once.
+type call struct {
+	done chan struct{}
+	h    *Handle
+	err  error
+}
+
+// Handle controls the GOMAXPROCS value applied by Set.
+type Handle struct {
+	cfg    config.Config
+	task   *ecstask.Task
+	result Result
+	trace  []TraceEvent
+	reset  bool
+	stop   chan struct{}
+
+	// drifted is the value GOMAXPROCS last drifted to, or 0 if it has not drifted.
+	drifted int
+
+	// applied is set once a resolution has been applied by the Handle.
+	applied bool
+
+	// refs is the number of callers holding the Handle, see Reset.
+	refs int
+	// refreshMu serializes refreshes, which resolve GOMAXPROCS without the state mutex.
+	refreshMu sync.Mutex
+
+	// computed is the result prior to being overridden, when overridden is set.
+	computed   Result
+	overridden bool
+}
+
+// Apply sets GOMAXPROCS in the same way as Set, returning a Handle to the applied value.
+//
+// Apply is safe to call concurrently and repeatedly. Concurrent calls share a single
+// resolution of GOMAXPROCS and, once set, subsequent calls return the existing Handle
+// until it is reset. If GOMAXPROCS could not be resolved, or in a dry run, the returned
+// Handle is not retained and the next call tries again.
+func Apply(opts ...config.Option) (*Handle, error) {
+	return apply(context.Background(), caller(), opts...)
+}
+
+// ApplyContext is like Apply but uses ctx for the metadata requests and retries.
+// If ctx is done before GOMAXPROCS is resolved, GOMAXPROCS is left unchanged and
+// an error wrapping ErrCanceled is returned.
+func ApplyContext(ctx context.Context, opts ...config.Option) (*Handle, error) {
+	return apply(ctx, caller(), opts...)
+}
+
+func apply(ctx context.Context, setBy string, opts ...config.Option) (*Handle, error) {
+	cfg := config.New(opts...)
+
+	h, err := applyOnce(ctx, cfg, setBy)
+	if err != nil && cfg.Strict && IsECS() {
+		panic(fmt.Errorf("%w: %w", ErrStrict, err))
+	}
+
+	return h, err
+}
+
+// applyOnce returns the Handle of the active Set, waits on the Set in flight, or
+// otherwise resolves and applies GOMAXPROCS.
+func applyOnce(ctx context.Context, cfg config.Config, setBy string) (*Handle, error) {
+	state.mu.Lock()
+
+	if h := state.handle; h != nil {
+		h.refs++
+		logEvent(cfg, slog.LevelDebug, "maxprocs: GOMAXPROCS already set",
+			[]slog.Attr{slog.Int(keyProcs, h.result.Procs), slog.String(keySetBy, h.result.SetBy)},
+			"maxprocs: GOMAXPROCS=%v already set by %s", h.result.Procs, h.result.SetBy)
+		state.mu.Unlock()
+
+		return h, nil
+	}
+
+	if c := state.inflight; c != nil {
+		state.mu.Unlock()
+		return c.wait(ctx, cfg, setBy)
+	}
+
+	h := newHandle(cfg, setBy)
+	c := &call{done: make(chan struct{}), h: h}
+	state.inflight = c
+	r := h.newResolution()
+	state.mu.Unlock()
+
+	r.resolve(ctx)
+
+	state.mu.Lock()
+	c.err = h.publish(r)
+	state.inflight = nil
+
+	// A dry run changes nothing, so it does not prevent a later Set applying GOMAXPROCS.
+	if c.err == nil && !h.cfg.DryRun {
+		state.handle = h
+		h.watch()
+	}
+	state.mu.Unlock()
+	close(c.done)
+
+	return h, c.err
+}
+
+// wait waits for the Set in flight, returning its Handle, or a Handle which was not
+// applied if ctx is done first.
+func (c *call) wait(ctx context.Context, cfg config.Config, setBy string) (*Handle, error) {
+	select {
+	case <-c.done:
+	case <-ctx.Done():
+		return newHandle(cfg, setBy), fmt.Errorf("%w: %w", ErrCanceled, ctx.Err())
+	}
+
+	if c.err != nil {
+		return c.h, c.err
+	}
+
+	if c.h.cfg.DryRun && !cfg.DryRun {
+		return applyOnce(ctx, cfg, setBy)
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	c.h.refs++
+
+	return c.h, nil
+}
+
+func newHandle(cfg config.Config, setBy string) *Handle {
+	h := &Handle{
+		cfg:    cfg,
+		task:   ecstask.New(cfg),
+		result: Result{Procs: prevMaxProcs(), Previous: prevMaxProcs(), PreviousMemoryLimit: prevMemoryLimit(), SetBy: setBy},
+		refs:   1,
+	}
+	h.result.Source = SourceNone
+	h.cfg.Trace = h.traceEvent
+
+	return h
+}
+
+// Current returns the current value of GOMAXPROCS.
+func (h *Handle) Current() int {
+	return runtime.GOMAXPROCS(0)
+}
+
+// Result returns the result of the last time GOMAXPROCS was set by the Handle.
+func (h *Handle) Result() Result {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return h.result
+}
+
+// Logger returns an option setting the logger of the Handle, as set by WithLogger or
+// WithSlog, such as for logging alongside it.
+func (h *Handle) Logger() config.Option {
+	return config.WithLoggerOf(h.cfg)
+}
+
+// Reset resets GOMAXPROCS, and the Go memory limit, if changed by the Handle. Once reset,
+// the Handle is released and a subsequent call to Set applies GOMAXPROCS again.
+//
+// Each call to Set, SetContext, Apply or ApplyContext returning the Handle holds it until
+// released by Reset or the returned function, and GOMAXPROCS is only reset once every
+// caller has released it. A library calling Set therefore never resets GOMAXPROCS set
+// by the program, such as with the blank import.
+//
+// On Go 1.25+ Reset restores the runtime default GOMAXPROCS, re-enabling the runtime's
+// periodic GOMAXPROCS updates, unless GOMAXPROCS is set in the environment. Otherwise, and
+// on earlier versions, it restores the previous value of GOMAXPROCS.
+func (h *Handle) Reset() {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if h.refs > 1 {
+		h.refs--
+		logEvent(h.cfg, slog.LevelDebug, "maxprocs: GOMAXPROCS still held by other callers, not resetting",
+			[]slog.Attr{slog.Int(keyRefs, h.refs)},
+			"maxprocs: GOMAXPROCS still held by %d other callers, not resetting", h.refs)
+
+		return
+	}
+
+	h.refs = 0
+
+	if state.handle == h {
+		state.handle = nil
+	}
+
+	if h.stop != nil {
+		close(h.stop)
+		h.stop = nil
+	}
+
+	// Once released the Handle is reset, whether or not it changed GOMAXPROCS, so it can
+	// no longer be refreshed or overridden.
+	released := h.reset
+	h.reset = true
+
+	if released || h.result.DryRun || !h.result.Source.changed() {
+		logEvent(h.cfg, slog.LevelInfo, "maxprocs: No GOMAXPROCS change to reset", nil,
+			"maxprocs: No GOMAXPROCS change to reset")
+
+		return
+	}
+
+	resetMaxProcs(h.cfg, h.result.Previous)
+
+	if h.result.MemoryLimit > 0 {
+		logEvent(h.cfg, slog.LevelInfo, "maxprocs: Resetting memory limit",
+			[]slog.Attr{slog.Int64(keyMemoryLimit, h.result.PreviousMemoryLimit)},
+			"maxprocs: Resetting memory limit to %v", h.result.PreviousMemoryLimit)
+		setMemoryLimit(h.result.PreviousMemoryLimit)
+	}
+}
+
+// Refresh resolves GOMAXPROCS again from the ECS metadata and applies it, reverting any
+// override. If GOMAXPROCS could not be resolved, the value previously applied is kept.
+// Refresh returns an error if the Handle has been reset.
+func (h *Handle) Refresh() (Result, error) {
+	return h.RefreshContext(context.Background())
+}
+
+// RefreshContext is like Refresh but uses ctx for the metadata requests and retries.
+func (h *Handle) RefreshContext(ctx context.Context) (Result, error) {
+	h.refreshMu.Lock()
+	defer h.refreshMu.Unlock()
+
+	state.mu.Lock()
+	if h.reset {
+		defer state.mu.Unlock()
+		return h.result, errHandleReset
+	}
+
+	r := h.newResolution()
+	state.mu.Unlock()
+
+	r.resolve(ctx)
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if h.reset {
+		return h.result, errHandleReset
+	}
+
+	err := h.publish(r)
+
+	return h.result, err
+}
+
+// Status returns the result of the active Set and true, or false if GOMAXPROCS
+// has not been set or has since been reset.
+func Status() (Result, bool) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if state.handle == nil {
+		return Result{}, false
+	}
+
+	return state.handle.result, true
+}
+
+// resolution is an attempt to resolve GOMAXPROCS, made without holding the state mutex.
+type resolution struct {
+	cfg    config.Config
+	task   *ecstask.Task
+	result Result
+	trace  []TraceEvent
+
+	procs    int
+	source   Source
+	memLimit int64
+	retries  uint64
+	err      error
+	// identity is the identity of the task and container for the EMF dimensions.
+	identity Identity
+}
+
+// newResolution returns a resolution for the Handle, starting from its result. The state
+// mutex must be held.
+func (h *Handle) newResolution() *resolution {
+	r := &resolution{cfg: h.cfg, task: h.task, result: h.result}
+	r.cfg.Trace = r.traceEvent
+	r.result.Source = SourceNone
+	r.result.MemoryLimit = 0
+
+	return r
+}
+
+// resolve resolves GOMAXPROCS and the Go memory limit without applying them.
+func (r *resolution) resolve(ctx context.Context) {
+	start, retries := time.Now(), r.task.Retries()
+
+	// Only the metadata fetched by this resolution is recorded for inspection.
+	r.task.ResetMetadata()
+
+	for _, err := range r.cfg.InvalidOptions {
+		logEvent(r.cfg, slog.LevelWarn, "maxprocs: Ignoring invalid option",
+			[]slog.Attr{slog.Any(keyError, err)},
+			"maxprocs: Ignoring invalid option: %v", err)
+	}
+
+	r.procs, r.source, r.memLimit, r.err = r.resolveLimits(ctx)
+
+	if r.cfg.EMF != nil && r.err == nil {
+		r.identity = r.resolveIdentity(ctx)
+	}
+	r.result.Duration = time.Since(start)
+	r.retries = r.task.Retries() - retries
+}
+
+// resolveIdentity returns the identity of the task and container, falling back to the cached
+// identity, then fetching it, when GOMAXPROCS was not resolved from the ECS metadata, such as
+// when set in the environment. Returns the zero identity if it can not be fetched.
+func (r *resolution) resolveIdentity(ctx context.Context) Identity {
+	if r.result.Identity != (Identity{}) {
+		return r.result.Identity
+	}
+
+	if id, ok := cachedIdentity(); ok {
+		return id
+	}
+
+	id, err := r.task.GetIdentity(ctx)
+	if err != nil {
+		return Identity{}
+	}
+
+	cacheIdentity(id)
+
+	return id
+}
+
+// publish applies the resolution, recording the outcome in the result and stats. If
+// the resolution failed, the Handle keeps the result previously applied. The state
+// mutex must be held.
+func (h *Handle) publish(r *resolution) (err error) {
+	defer func() {
+		state.stats.record(r.result, r.retries, err)
+		state.last.record(r, err)
+	}()
+
+	if r.err != nil {
+		r.result.Procs = prevMaxProcs()
+		if !h.applied {
+			h.result = r.result
+		}
+
+		return r.err
+	}
+
+	h.applied = true
+
+	if h.overridden {
+		h.revert()
+	}
+
+	h.resetStaleMaxProcs(r)
+	h.resetStaleMemoryLimit(r)
+
+	r.result.Procs = prevMaxProcs()
+	h.trace = r.trace
+
+	if r.procs == 0 {
+		h.result = r.result
+		h.writeEMF(r.identity)
+
+		return nil
+	}
+
+	if r.cfg.DryRun {
+		r.dryRun()
+		h.result = r.result
+		h.writeEMF(r.identity)
+
+		return nil
+	}
+
+	setMaxProcs(r.procs)
+
+	r.result.Procs = r.procs
+	r.result.Source = r.source
+
+	if r.memLimit > 0 {
+		setMemoryLimit(r.memLimit)
+
+		r.result.MemoryLimit = r.memLimit
+	}
+
+	logEvent(r.cfg, slog.LevelInfo, "maxprocs: Updated GOMAXPROCS", resultAttrs(r.result),
+		"maxprocs: Updated GOMAXPROCS=%v", r.procs)
+
+	h.result = r.result
+	h.writeEMF(r.identity)
+
+	return nil
+}
+
+// resetStaleMaxProcs resets GOMAXPROCS applied by the Handle when the resolution no longer
+// sets it, such as when now set in the environment. The state mutex must be held.
+func (h *Handle) resetStaleMaxProcs(r *resolution) {
+	if !h.result.Source.changed() || h.result.DryRun || (r.procs > 0 && !r.cfg.DryRun) {
+		return
+	}
+
+	resetMaxProcs(h.cfg, h.result.Previous)
+}
+
+// resetStaleMemoryLimit resets the Go memory limit applied by the Handle when the
+// resolution no longer sets it. The state mutex must be held.
+func (h *Handle) resetStaleMemoryLimit(r *resolution) {
+	if h.result.MemoryLimit == 0 || h.result.DryRun || (r.memLimit > 0 && r.procs > 0 && !r.cfg.DryRun) {
+		return
+	}
+
+	logEvent(h.cfg, slog.LevelInfo, "maxprocs: Resetting memory limit",
+		[]slog.Attr{slog.Int64(keyMemoryLimit, h.result.PreviousMemoryLimit)},
+		"maxprocs: Resetting memory limit to %v", h.result.PreviousMemoryLimit)
+	setMemoryLimit(h.result.PreviousMemoryLimit)
+}
+
+// dryRun records and logs the values GOMAXPROCS and the memory limit would be set to,
+// alongside their current values, without changing them.
+func (r *resolution) dryRun() {
+	procs, memLimit := r.procs, r.memLimit
+
+	r.result.DryRun = true
+	r.result.Procs = procs
+	r.result.Source = r.source
+	r.result.MemoryLimit = memLimit
+
+	current, currentMemLimit := prevMaxProcs(), prevMemoryLimit()
+	attrs := append(resultAttrs(r.result),
+		slog.Int(keyCurrent, current), slog.Int64(keyCurrentMemoryLimit, currentMemLimit))
+
+	if memLimit > 0 {
+		logEvent(r.cfg, slog.LevelInfo, "maxprocs: Dry run, would update GOMAXPROCS", attrs,
+			"maxprocs: Dry run, would update GOMAXPROCS=%v (currently %v) and memory limit=%v (currently %v)",
+			procs, current, memLimit, currentMemLimit)
+
+		return
+	}
+
+	logEvent(r.cfg, slog.LevelInfo, "maxprocs: Dry run, would update GOMAXPROCS", attrs,
+		"maxprocs: Dry run, would update GOMAXPROCS=%v (currently %v)", procs, current)
+}
+
+// resolveLimits resolves the value GOMAXPROCS should be set to along with its source, and the
+// Go memory limit. A value of 0 is returned when GOMAXPROCS should be left unchanged,
+// in which case the result source is set, or when the memory limit should be left unchanged.
+//
+//nolint:cyclop,funlen // resolution is easier to follow as a single sequence of steps.
+func (r *resolution) resolveLimits(ctx context.Context) (int, Source, int64, error) {
+	cfg := r.cfg
+
+	envProcs, env, hasEnv := envMaxProcs(cfg)
+	if hasEnv && cfg.EnvPolicy == config.EnvHonor {
+		r.result.Source = SourceEnv
+		logEvent(cfg, slog.LevelInfo, "maxprocs: Honoring GOMAXPROCS as set in environment",
+			[]slog.Attr{slog.Int(keyProcs, envProcs), slog.String(keySource, string(SourceEnv))},
+			"maxprocs: Honoring GOMAXPROCS=%q as set in environment", env)
+
+		return 0, SourceNone, 0, nil
+	}
+
+	if hasEnv && cfg.EnvPolicy == config.EnvIgnore {
+		logEvent(cfg, slog.LevelInfo, "maxprocs: Ignoring GOMAXPROCS set in environment as per env policy",
+			[]slog.Attr{slog.String(keyEnv, env)},
+			"maxprocs: Ignoring GOMAXPROCS=%q set in environment as per env policy", env)
+
+		hasEnv = false
+	}
+
+	if shouldDeferToRuntime(cfg) {
+		r.result.Source = SourceRuntime
+		return 0, SourceNone, 0, nil
+	}
+
+	limits, err := r.task.GetLimits(ctx)
+	r.result.ContainerCPU, r.result.TaskCPU = limits.ContainerCPU, limits.TaskCPU
+	r.result.ContainerMemory, r.result.TaskMemory = limits.ContainerMemory, limits.TaskMemory
+	r.result.Identity = limits.Identity
+
+	for _, field := range limits.Unusable {
+		logEvent(cfg, slog.LevelWarn, "maxprocs: Ignoring unusable ECS metadata limit",
+			[]slog.Attr{slog.String(keyField, field)},
+			"maxprocs: Ignoring unusable ECS metadata limit %s", field)
+	}
+
+	if err == nil {
+		cacheIdentity(limits.Identity)
+	}
+
+	if err == nil && limits.MatchedBy == "" && limits.ContainerCPU > 0 {
+		logEvent(cfg, slog.LevelWarn, "maxprocs: Container not found in task metadata, using container CPU limit",
+			[]slog.Attr{slog.Float64(keyContainerCPU, limits.ContainerCPU)},
+			"maxprocs: Container not found in task metadata, using container CPU limit of %v", limits.ContainerCPU)
+	} else if err == nil && limits.MatchedBy == "" {
+		logEvent(cfg, slog.LevelWarn, "maxprocs: Container not found in task metadata, using task CPU limit",
+			[]slog.Attr{slog.Float64(keyTaskCPU, limits.TaskCPU)},
+			"maxprocs: Container not found in task metadata, using task CPU limit of %v", limits.TaskCPU)
+	}
+
+	if err != nil && ctx.Err() != nil {
+		logEvent(cfg, slog.LevelWarn, "maxprocs: Canceled setting GOMAXPROCS",
+			[]slog.Attr{slog.Any(keyError, ctx.Err())},
+			"maxprocs: Canceled setting GOMAXPROCS: %v", ctx.Err())
+
+		return 0, SourceNone, 0, fmt.Errorf("%w: %w", ErrCanceled, ctx.Err())
+	}
+
+	if err != nil {
+		logEvent(cfg, slog.LevelError, "maxprocs: Failed to set GOMAXPROCS",
+			[]slog.Attr{slog.Any(keyError, err)},
+			"maxprocs: Failed to set GOMAXPROCS: %v", err)
+
+		return 0, SourceNone, 0, fmt.Errorf("failed to set GOMAXPROCS: %w", err)
+	}
+
+	tuning := r.tuning(limits)
+	if tuning.Disable {
+		logEvent(cfg, slog.LevelInfo, "maxprocs: Setting GOMAXPROCS disabled by tuning override", nil,
+			"maxprocs: Setting GOMAXPROCS disabled by tuning override")
+
+		return 0, SourceNone, 0, nil
+	}
+
+	procs, source := tunedMaxProcs(limits, tuning)
+
+	if hasEnv && cfg.EnvPolicy == config.EnvClamp {
+		attrs := []slog.Attr{slog.String(keyEnv, env), slog.Int(keyProcs, procs)}
+
+		if envProcs <= procs {
+			r.result.Source = SourceEnv
+			logEvent(cfg, slog.LevelInfo, "maxprocs: Honoring GOMAXPROCS as set in environment within ECS limit", attrs,
+				"maxprocs: Honoring GOMAXPROCS=%q as set in environment within ECS limit of %v", env, procs)
+
+			return 0, SourceNone, 0, nil
+		}
+
+		logEvent(cfg, slog.LevelInfo, "maxprocs: Clamping GOMAXPROCS as set in environment to ECS limit", attrs,
+			"maxprocs: Clamping GOMAXPROCS=%q as set in environment to ECS limit of %v", env, procs)
+	}
+
+	return procs, source, memoryLimit(cfg, limits, tuning), nil
+}
+
+// caller returns the name of the function which called the exported function
+// calling caller.
+func caller() string {
+	const skip = 2 // skip caller and the exported function.
+
+	pc, _, _, ok := runtime.Caller(skip)
+	if !ok {
+		return "unknown"
+	}
+
+	if fn := runtime.FuncForPC(pc); fn != nil {
+		return fn.Name()
+	}
+
+	return "unknown"
+}
@@ -0,0 +1,142 @@
+package maxprocs
+
+import (
+	"encoding/json"
+	"log/slog"
+	"runtime"
+	"time"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+)
+
+// TraceEvent is a step taken resolving GOMAXPROCS, as logged.
+type TraceEvent struct {
+	Time    time.Time
+	Level   slog.Level
+	Message string
+}
+
+// Options describes the options in effect when resolving GOMAXPROCS.
+type Options struct {
+	ContainerMetadataURI string
+	TaskMetadataURI      string
+	TaskTags             bool
+	RuntimePolicy        string
+	EnvPolicy            string
+	MaxRetries           int
+	RetryBackoff         time.Duration
+	WatchdogInterval     time.Duration
+	WatchdogReapply      bool
+	HTTPTimeout          time.Duration
+	MaxResponseSize      int64
+	Procs                int
+	Headroom             float64
+	MemoryLimitRatio     float64
+	Disable              bool
+	DryRun               bool
+	Strict               bool
+	EMF                  bool
+}
+
+// Inspection describes the last attempt to set GOMAXPROCS in the process alongside the
+// current state of the runtime, for debugging.
+type Inspection struct {
+	// Result is the result of the last attempt, whether or not it succeeded, or of the
+	// last override or revert.
+	Result Result
+	// Error is the error of the last attempt, or empty if it succeeded.
+	Error string
+	// Active is true if GOMAXPROCS is set by a Handle which has not been reset.
+	Active bool
+	// Trace is the steps taken resolving GOMAXPROCS in the last attempt.
+	Trace []TraceEvent
+	// Options is the options in effect in the last attempt. Tuning overrides from
+	// container labels and tags are not included, but are in the trace.
+	Options Options
+	// ContainerMetadata is the raw container metadata used in the last attempt, if fetched.
+	ContainerMetadata json.RawMessage
+	// TaskMetadata is the raw task metadata used in the last attempt, if fetched.
+	TaskMetadata json.RawMessage
+	// GOMAXPROCS is the current value of GOMAXPROCS.
+	GOMAXPROCS int
+	// MemoryLimit is the current Go memory limit in bytes.
+	MemoryLimit int64
+	// NumCPU is the number of CPUs usable by the process, as reported by the runtime.
+	NumCPU int
+}
+
+// Inspect returns the inspection of the last attempt to set GOMAXPROCS.
+func Inspect() Inspection {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	last := state.last
+
+	return Inspection{
+		Result:            state.stats.Last,
+		Error:             last.err,
+		Active:            state.handle != nil,
+		Trace:             last.trace,
+		Options:           last.options,
+		ContainerMetadata: last.containerMeta,
+		TaskMetadata:      last.taskMeta,
+		GOMAXPROCS:        runtime.GOMAXPROCS(0),
+		MemoryLimit:       prevMemoryLimit(),
+		NumCPU:            runtime.NumCPU(),
+	}
+}
+
+// attempt records the last attempt to set GOMAXPROCS for inspection.
+type attempt struct {
+	err           string
+	options       Options
+	trace         []TraceEvent
+	containerMeta []byte
+	taskMeta      []byte
+}
+
+// record records the attempt of the resolution. The state mutex must be held.
+func (a *attempt) record(r *resolution, err error) {
+	a.err = ""
+	if err != nil {
+		a.err = err.Error()
+	}
+
+	a.options = newOptions(r.cfg)
+	a.trace = r.trace
+	a.containerMeta, a.taskMeta = r.task.Metadata()
+}
+
+// traceEvent appends the event to the trace of the Handle. Events are only logged by the
+// Handle when the state mutex is held.
+func (h *Handle) traceEvent(level slog.Level, msg string) {
+	h.trace = append(h.trace, TraceEvent{Time: time.Now(), Level: level, Message: msg})
+}
+
+// traceEvent appends the event to the trace of the resolution.
+func (r *resolution) traceEvent(level slog.Level, msg string) {
+	r.trace = append(r.trace, TraceEvent{Time: time.Now(), Level: level, Message: msg})
+}
+
+func newOptions(cfg config.Config) Options {
+	return Options{
+		ContainerMetadataURI: cfg.ContainerMetadataURI,
+		TaskMetadataURI:      cfg.TaskMetadataURI,
+		TaskTags:             cfg.TaskTags,
+		RuntimePolicy:        cfg.RuntimePolicy.String(),
+		EnvPolicy:            cfg.EnvPolicy.String(),
+		MaxRetries:           cfg.Retry.MaxRetries,
+		RetryBackoff:         cfg.Retry.Backoff,
+		WatchdogInterval:     cfg.Watchdog.Interval,
+		WatchdogReapply:      cfg.Watchdog.Reapply,
+		HTTPTimeout:          cfg.Client.HTTPTimeout,
+		MaxResponseSize:      cfg.Client.MaxResponseSize,
+		Procs:                cfg.Tuning.Procs,
+		Headroom:             cfg.Tuning.Headroom,
+		MemoryLimitRatio:     cfg.Tuning.MemoryLimitRatio,
+		Disable:              cfg.Tuning.Disable,
+		DryRun:               cfg.DryRun,
+		Strict:               cfg.Strict,
+		EMF:                  cfg.EMF != nil,
+	}
+}
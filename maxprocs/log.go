@@ -0,0 +1,60 @@
+package maxprocs
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+)
+
+// Structured log attribute keys.
+const (
+	keyProcs              = "procs"
+	keyPrevious           = "previous"
+	keySource             = "source"
+	keyContainerCPU       = "container_cpu"
+	keyTaskCPU            = "task_cpu"
+	keyDuration           = "duration"
+	keySetBy              = "set_by"
+	keyEnv                = "env"
+	keyGOMEMLIMIT         = "gomemlimit"
+	keyCgroupQuota        = "cgroup_quota"
+	keyError              = "error"
+	keyField              = "field"
+	keyFrom               = "from"
+	keyKey                = "key"
+	keyValue              = "value"
+	keyMemoryLimit        = "memory_limit"
+	keyCurrent            = "current"
+	keyCurrentMemoryLimit = "current_memory_limit"
+	keyRefs               = "refs"
+)
+
+// logEvent logs an event to the printf logger, as format and args, and to the
+// structured logger, as msg and attrs, tracing it as formatted for the printf logger.
+func logEvent(cfg config.Config, level slog.Level, msg string, attrs []slog.Attr, format string, args ...any) {
+	cfg.Log(format, args...)
+	cfg.LogAttrs(level, msg, attrs...)
+
+	if cfg.Trace != nil {
+		cfg.Trace(level, fmt.Sprintf(format, args...))
+	}
+}
+
+// resultAttrs returns the structured log attributes describing the result.
+func resultAttrs(res Result) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.Int(keyProcs, res.Procs),
+		slog.Int(keyPrevious, res.Previous),
+		slog.String(keySource, string(res.Source)),
+		slog.Float64(keyContainerCPU, res.ContainerCPU),
+		slog.Float64(keyTaskCPU, res.TaskCPU),
+		slog.Duration(keyDuration, res.Duration),
+	}
+
+	if res.MemoryLimit > 0 {
+		attrs = append(attrs, slog.Int64(keyMemoryLimit, res.MemoryLimit))
+	}
+
+	return attrs
+}
@@ -2,54 +2,105 @@ package maxprocs
 
 import (
 	"context"
-	"fmt"
+	"io"
+	"log/slog"
 	"os"
 	"runtime"
+	"strconv"
+	"sync"
+	"time"
 
+	"github.com/rdforte/gomaxecs/internal/cgroup"
 	"github.com/rdforte/gomaxecs/internal/config"
-	ecstask "github.com/rdforte/gomaxecs/internal/task"
 )
 
-const maxProcsKey = "GOMAXPROCS"
+const (
+	maxProcsKey = "GOMAXPROCS"
+	minProcs    = 1
+)
 
 // Set sets GOMAXPROCS based on the CPU limit of the container and the task.
-// returns a function to reset GOMAXPROCS to its previous value and an error if one occurred.
-// If the GOMAXPROCS environment variable is set, it will honor that value.
+// returns a function to reset GOMAXPROCS and an error if one occurred.
+// If the GOMAXPROCS environment variable is set to a valid value, it is handled
+// according to the env policy, by default honoring that value. Invalid values
+// are reported and ignored.
+//
+// On Go 1.25+ the returned function restores the runtime default GOMAXPROCS,
+// re-enabling the runtime's periodic GOMAXPROCS updates, unless GOMAXPROCS is set in
+// the environment. Otherwise, and on earlier versions, it restores the previous value
+// of GOMAXPROCS.
+//
+// Set is safe to call concurrently and repeatedly, see Apply. The returned function
+// only resets GOMAXPROCS once every caller has reset it, see Handle.Reset, and calling
+// it more than once has no further effect.
 func Set(opts ...config.Option) (func(), error) {
-	cfg := config.New(opts...)
-	task := ecstask.New(cfg)
+	h, err := apply(context.Background(), caller(), opts...)
+
+	return sync.OnceFunc(h.Reset), err
+}
+
+// SetContext is like Set but uses ctx for the metadata requests and retries,
+// allowing the caller to bound or cancel how long setting GOMAXPROCS may block.
+// If ctx is done before GOMAXPROCS is resolved, GOMAXPROCS is left unchanged and
+// an error wrapping ErrCanceled is returned.
+func SetContext(ctx context.Context, opts ...config.Option) (func(), error) {
+	h, err := apply(ctx, caller(), opts...)
+
+	return sync.OnceFunc(h.Reset), err
+}
 
-	undoNoop := func() {
-		cfg.Log("maxprocs: No GOMAXPROCS change to reset")
+// envMaxProcs returns the GOMAXPROCS environment variable, parsed and as set, and a
+// boolean indicating if it is present and valid. As with the Go runtime, only positive integers are
+// valid. Invalid values are reported and ignored.
+func envMaxProcs(cfg config.Config) (int, string, bool) {
+	env, ok := os.LookupEnv(maxProcsKey)
+	if !ok {
+		return 0, "", false
 	}
 
-	if procs, ok := shouldHonorGOMAXPROCSEnv(); ok {
-		cfg.Log("maxprocs: Honoring GOMAXPROCS=%q as set in environment", procs)
-		return undoNoop, nil
+	procs, err := strconv.Atoi(env)
+	if err != nil || procs < minProcs {
+		logEvent(cfg, slog.LevelWarn, "maxprocs: Ignoring invalid GOMAXPROCS set in environment",
+			[]slog.Attr{slog.String(keyEnv, env)},
+			"maxprocs: Ignoring invalid GOMAXPROCS=%q set in environment, must be a positive integer", env)
+		return 0, "", false
 	}
 
-	prevProcs := prevMaxProcs()
-	undo := func() {
-		cfg.Log("maxprocs: Resetting GOMAXPROCS to %v", prevProcs)
-		setMaxProcs(prevProcs)
+	return procs, env, true
+}
+
+// shouldDeferToRuntime returns true if GOMAXPROCS should be left to the Go runtime.
+// This is only the case when the runtime is container aware, the runtime policy is
+// RuntimeDefer and a cgroup CPU quota exists. Otherwise, GOMAXPROCS is overridden.
+func shouldDeferToRuntime(cfg config.Config) bool {
+	if cfg.RuntimePolicy != config.RuntimeDefer {
+		return false
 	}
 
-	procs, err := task.GetMaxProcs(context.Background())
-	if err != nil {
-		cfg.Log("maxprocs: Failed to set GOMAXPROCS:", err)
-		return undo, fmt.Errorf("failed to set GOMAXPROCS: %w", err)
+	if !runtimeContainerAware() {
+		logEvent(cfg, slog.LevelInfo, "maxprocs: Not deferring to Go runtime as it is not container aware", nil,
+			"maxprocs: Not deferring to Go runtime as it is not container aware, requires Go 1.25+ "+
+				"and the containermaxprocs GODEBUG setting")
+
+		return false
 	}
 
-	setMaxProcs(procs)
-	cfg.Log("maxprocs: Updated GOMAXPROCS=%v", procs)
+	quota, ok, err := cgroup.CPUQuota(cfg.CgroupFS)
+	if err != nil {
+		logEvent(cfg, slog.LevelWarn, "maxprocs: Failed to read cgroup CPU quota",
+			[]slog.Attr{slog.Any(keyError, err)},
+			"maxprocs: Failed to read cgroup CPU quota: %v", err)
+		return false
+	}
 
-	return undo, nil
-}
+	if ok {
+		procs := prevMaxProcs()
+		logEvent(cfg, slog.LevelInfo, "maxprocs: Deferring to Go runtime as cgroup CPU quota is set",
+			[]slog.Attr{slog.Int(keyProcs, procs), slog.Float64(keyCgroupQuota, quota)},
+			"maxprocs: Deferring to Go runtime GOMAXPROCS=%v as cgroup CPU quota of %v is set", procs, quota)
+	}
 
-// shouldHonorGOMAXPROCSEnv returns the GOMAXPROCS environment variable if present
-// and a boolean indicating if it should be honored.
-func shouldHonorGOMAXPROCSEnv() (string, bool) {
-	return os.LookupEnv(maxProcsKey)
+	return ok
 }
 
 func prevMaxProcs() int {
@@ -65,6 +116,120 @@ func WithLogger(printf func(format string, args ...any)) config.Option {
 	return config.WithLogger(printf)
 }
 
+// WithSlog sets the structured logger. By default, no structured logger is set.
+func WithSlog(logger *slog.Logger) config.Option {
+	return config.WithSlog(logger)
+}
+
+// WithRetry sets the max number of times a failed metadata request is retried and
+// the backoff before the first retry, which doubles on each subsequent retry.
+// By default, failed requests are not retried.
+func WithRetry(maxRetries int, backoff time.Duration) config.Option {
+	return config.WithRetry(maxRetries, backoff)
+}
+
+// WithWatchdog checks every interval, until reset, that GOMAXPROCS is still the value
+// set, as other code calling runtime.GOMAXPROCS, such as an automaxprocs import, silently
+// undoes it. Drift is logged and counted in the stats and, if reapply is true, the value
+// is set again. By default, there is no watchdog.
+func WithWatchdog(interval time.Duration, reapply bool) config.Option {
+	return config.WithWatchdog(interval, reapply)
+}
+
+// WithMaxResponseSize sets the max size in bytes of a metadata response. Larger responses
+// fail without being read in full. Defaults to 1 MiB.
+func WithMaxResponseSize(size int64) config.Option {
+	return config.WithMaxResponseSize(size)
+}
+
+// WithTaskTags reads gomaxecs prefixed task and container instance tags as overrides, in the
+// same way as container labels, from the task metadata with tags. This requires the ECS agent
+// to have tag support enabled and the task role to allow listing tags. If the tags are
+// unavailable the task metadata without tags is used. By default, tags are not read.
+func WithTaskTags() config.Option {
+	return config.WithTaskTags()
+}
+
+// WithDryRun resolves GOMAXPROCS and the memory limit in full, logging and reporting
+// in the Result the values they would be set to alongside their current values, without
+// changing them. The returned function to reset GOMAXPROCS is then a no-op. A dry run
+// is not retained as the active Set, so a later Set without dry run applies GOMAXPROCS.
+// By default, dry run is disabled.
+func WithDryRun() config.Option {
+	return config.WithDryRun()
+}
+
+// WithStrict panics, with an error wrapping ErrStrict, when an ECS environment is
+// detected but GOMAXPROCS could not be resolved, rather than returning the error and
+// leaving GOMAXPROCS unchanged. Running with GOMAXPROCS set to the host CPU count can be
+// worse than failing to start. By default, strict mode is disabled.
+func WithStrict() config.Option {
+	return config.WithStrict()
+}
+
+// WithEMF writes an EMF document to w, typically os.Stdout, each time GOMAXPROCS is
+// resolved, with GOMAXPROCS and the ECS CPU and memory limits as metrics in the gomaxecs
+// namespace, dimensioned by cluster, service and task family. With the awslogs log driver
+// the document is extracted as CloudWatch metrics. By default, no EMF document is written.
+func WithEMF(w io.Writer) config.Option {
+	return config.WithEMF(w)
+}
+
+// WithHeadroom sets the fraction of the CPU limit held back when setting GOMAXPROCS,
+// e.g. 0.25 sets GOMAXPROCS to 3 for a limit of 4 vCPUs. GOMAXPROCS is at least 1.
+// Values outside of [0, 1) are logged and ignored. By default, no headroom is held back.
+// Can be overridden by the gomaxecs.headroom container label or tag.
+func WithHeadroom(headroom float64) config.Option {
+	return config.WithHeadroom(headroom)
+}
+
+// WithMemoryLimitRatio sets the Go memory limit to the fraction of the container memory
+// limit, falling back to the task memory limit, alongside setting GOMAXPROCS. The memory
+// limit is left unchanged if the GOMEMLIMIT environment variable is set. Values outside
+// of [0, 1] are logged and ignored. By default, the memory limit is left unchanged.
+// Can be overridden by the gomaxecs.memlimit-ratio container label or tag.
+func WithMemoryLimitRatio(ratio float64) config.Option {
+	return config.WithMemoryLimitRatio(ratio)
+}
+
+// RuntimePolicy determines how GOMAXPROCS is set when the Go runtime is
+// container aware (Go 1.25+) and a cgroup CPU quota exists.
+type RuntimePolicy = config.RuntimePolicy
+
+const (
+	// RuntimeOverride sets GOMAXPROCS based on the ECS metadata. This is the default.
+	RuntimeOverride = config.RuntimeOverride
+	// RuntimeDefer leaves GOMAXPROCS to the Go runtime when a cgroup CPU quota exists,
+	// as the runtime already sets GOMAXPROCS based on it. Has no effect prior to Go 1.25,
+	// or when the containermaxprocs GODEBUG setting is disabled, which is the default
+	// when the main module's go.mod declares a Go version prior to 1.25.
+	RuntimeDefer = config.RuntimeDefer
+)
+
+// WithRuntimePolicy sets the runtime policy. By default, RuntimeOverride is used.
+func WithRuntimePolicy(policy RuntimePolicy) config.Option {
+	return config.WithRuntimePolicy(policy)
+}
+
+// EnvPolicy determines how a valid GOMAXPROCS environment variable is treated.
+type EnvPolicy = config.EnvPolicy
+
+const (
+	// EnvHonor leaves GOMAXPROCS as set in the environment. This is the default.
+	EnvHonor = config.EnvHonor
+	// EnvIgnore ignores the GOMAXPROCS environment variable and sets GOMAXPROCS based on the ECS metadata.
+	EnvIgnore = config.EnvIgnore
+	// EnvClamp honors the GOMAXPROCS environment variable but limits it to the ECS CPU limit.
+	// If the ECS CPU limit can not be resolved, GOMAXPROCS is left as set in the environment,
+	// but an error is returned as with the other policies, which panics with WithStrict.
+	EnvClamp = config.EnvClamp
+)
+
+// WithEnvPolicy sets the GOMAXPROCS environment variable policy. By default, EnvHonor is used.
+func WithEnvPolicy(policy EnvPolicy) config.Option {
+	return config.WithEnvPolicy(policy)
+}
+
 // IsECS returns true if detected ECS environment.
 func IsECS() bool {
 	return len(config.GetECSMetadataURI()) > 0
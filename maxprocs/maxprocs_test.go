@@ -2,14 +2,24 @@ package maxprocs_test
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"fmt"
 	"log"
+	"log/slog"
+	"math"
+	"os"
 	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
+	"github.com/rdforte/gomaxecs/internal/config"
 	"github.com/rdforte/gomaxecs/internal/task/tasktest"
 	"github.com/rdforte/gomaxecs/maxprocs"
 )
@@ -28,8 +38,9 @@ func TestMaxProcs_Set_SuccessfullySetsGOMAXPROCS(t *testing.T) {
 		SetMetaURIEnv()
 	defer agent.Close()
 
-	_, err := maxprocs.Set()
+	undo, err := maxprocs.Set()
 	require.NoError(t, err)
+	defer undo()
 
 	procs := runtime.GOMAXPROCS(0)
 	wantProcs := 2
@@ -105,36 +116,447 @@ func TestMaxProcs_Set_LoggerShouldLog(t *testing.T) {
 			buf := new(bytes.Buffer)
 			logger := log.New(buf, "", 0)
 
-			_, _ = maxprocs.Set(maxprocs.WithLogger(logger.Printf))
+			undo, _ := maxprocs.Set(maxprocs.WithLogger(logger.Printf))
+			defer undo()
 
 			assert.Contains(t, buf.String(), tt.wantLog)
 		})
 	}
 }
 
-func TestMaxProcs_Set_UndoLogsNoChangesWhenHonorsGOMAXPROCSEnv(t *testing.T) {
-	t.Setenv("GOMAXPROCS", "4")
+func TestMaxProcs_Set_GOMAXPROCSEnv(t *testing.T) {
+	tableTest := []struct {
+		name      string
+		env       string
+		policy    maxprocs.EnvPolicy
+		wantProcs int
+		wantLog   string
+	}{
+		{
+			name:      "should ignore empty GOMAXPROCS env and set GOMAXPROCS from ECS",
+			env:       "",
+			policy:    maxprocs.EnvHonor,
+			wantProcs: 2,
+			wantLog:   "maxprocs: Ignoring invalid GOMAXPROCS=\"\" set in environment, must be a positive integer",
+		},
+		{
+			name:      "should ignore GOMAXPROCS env of 0 and set GOMAXPROCS from ECS",
+			env:       "0",
+			policy:    maxprocs.EnvHonor,
+			wantProcs: 2,
+			wantLog:   "maxprocs: Ignoring invalid GOMAXPROCS=\"0\" set in environment, must be a positive integer",
+		},
+		{
+			name:      "should ignore non numeric GOMAXPROCS env and set GOMAXPROCS from ECS",
+			env:       "abc",
+			policy:    maxprocs.EnvHonor,
+			wantProcs: 2,
+			wantLog:   "maxprocs: Ignoring invalid GOMAXPROCS=\"abc\" set in environment, must be a positive integer",
+		},
+		{
+			name:      "should honor valid GOMAXPROCS env when policy is EnvHonor",
+			env:       "4",
+			policy:    maxprocs.EnvHonor,
+			wantProcs: 1,
+			wantLog:   "maxprocs: Honoring GOMAXPROCS=\"4\" as set in environment",
+		},
+		{
+			name:      "should ignore valid GOMAXPROCS env when policy is EnvIgnore",
+			env:       "4",
+			policy:    maxprocs.EnvIgnore,
+			wantProcs: 2,
+			wantLog:   "maxprocs: Ignoring GOMAXPROCS=\"4\" set in environment as per env policy",
+		},
+		{
+			name:      "should clamp GOMAXPROCS env to ECS limit when policy is EnvClamp",
+			env:       "4",
+			policy:    maxprocs.EnvClamp,
+			wantProcs: 2,
+			wantLog:   "maxprocs: Clamping GOMAXPROCS=\"4\" as set in environment to ECS limit of 2",
+		},
+		{
+			name:      "should honor GOMAXPROCS env within ECS limit when policy is EnvClamp",
+			env:       "2",
+			policy:    maxprocs.EnvClamp,
+			wantProcs: 1,
+			wantLog:   "maxprocs: Honoring GOMAXPROCS=\"2\" as set in environment within ECS limit of 2",
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			runtime.GOMAXPROCS(1)
+			t.Setenv("GOMAXPROCS", tt.env)
+
+			agent := tasktest.NewECSAgent(t).
+				WithContainerMetaEndpoint(containerCPU).
+				WithTaskMetaEndpoint(containerCPU, taskCPU).
+				Start().
+				SetMetaURIEnv()
+			defer agent.Close()
+
+			buf := new(bytes.Buffer)
+			logger := log.New(buf, "", 0)
+
+			undo, _ := maxprocs.Set(maxprocs.WithLogger(logger.Printf), maxprocs.WithEnvPolicy(tt.policy))
+			defer undo()
+
+			assert.Equal(t, tt.wantProcs, runtime.GOMAXPROCS(0))
+			assert.Contains(t, buf.String(), tt.wantLog)
+		})
+	}
+}
+
+func TestMaxProcs_Set_SlogShouldLogAttrs(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	runtime.GOMAXPROCS(1)
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+
+	undo, err := maxprocs.Set(maxprocs.WithSlog(logger))
+	require.NoError(t, err)
+	defer undo()
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+
+	assert.Equal(t, "INFO", got["level"])
+	assert.Equal(t, "maxprocs: Updated GOMAXPROCS", got["msg"])
+	assert.InDelta(t, 2, got["procs"], 0)
+	assert.InDelta(t, 1, got["previous"], 0)
+	assert.Equal(t, "ecs", got["source"])
+	assert.InDelta(t, containerCPU, got["container_cpu"], 0)
+	assert.InDelta(t, taskCPU, got["task_cpu"], 0)
+	assert.Contains(t, got, "duration")
+}
+
+func TestMaxProcs_Set_SlogShouldLogEnvValuesAsSet(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	t.Setenv("GOMAXPROCS", "3")
+	t.Setenv("GOMEMLIMIT", "1GiB")
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+
+	undo, err := maxprocs.Set(
+		maxprocs.WithEnvPolicy(maxprocs.EnvIgnore), maxprocs.WithMemoryLimitRatio(0.5), maxprocs.WithSlog(logger))
+	require.NoError(t, err)
+	defer undo()
+
+	got := make(map[string]map[string]any)
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var attrs map[string]any
+		require.NoError(t, json.Unmarshal(line, &attrs))
+
+		msg, _ := attrs["msg"].(string)
+		got[msg] = attrs
+	}
+
+	assert.Equal(t, "3", got["maxprocs: Ignoring GOMAXPROCS set in environment as per env policy"]["env"])
+
+	gomemlimit := got["maxprocs: Honoring GOMEMLIMIT as set in environment"]
+	assert.Equal(t, "1GiB", gomemlimit["gomemlimit"])
+	assert.NotContains(t, gomemlimit, "env")
+}
+
+func TestMaxProcs_Set_SlogShouldLogErrorWhenFailToGetMaxProcs(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointInternalServerError().
+		WithTaskMetaEndpointInternalServerError().
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewJSONHandler(buf, nil))
+
+	_, err := maxprocs.Set(maxprocs.WithSlog(logger))
+	require.Error(t, err)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &got))
+
+	assert.Equal(t, "ERROR", got["level"])
+	assert.Equal(t, "maxprocs: Failed to set GOMAXPROCS", got["msg"])
+	assert.Contains(t, got["error"], "request failed, status code: 500")
+}
+
+func TestMaxProcs_Set_LogsUnusableMetadataLimits(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(`{"DockerId":"container-id","Limits":{"CPU":"two"}}`).
+		WithTaskMetaEndpointJSON(`{"Containers":[{"DockerId":"container-id"}],"Limits":{"CPU":"2"}}`).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
 
 	buf := new(bytes.Buffer)
 	logger := log.New(buf, "", 0)
 
-	undo, _ := maxprocs.Set(maxprocs.WithLogger(logger.Printf))
+	undo, err := maxprocs.Set(maxprocs.WithLogger(logger.Printf))
+	require.NoError(t, err)
+	defer undo()
 
-	undo()
+	assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+	assert.Contains(t, buf.String(), `maxprocs: Ignoring unusable ECS metadata limit container Limits.CPU="two"`)
+}
 
-	assert.Contains(t, buf.String(), "maxprocs: No GOMAXPROCS change to reset")
+func TestMaxProcs_Set_WarnsWhenContainerNotFoundInTaskMetadata(t *testing.T) {
+	tableTest := []struct {
+		name          string
+		containerMeta string
+		wantProcs     int
+		wantLog       string
+	}{
+		{
+			name:          "should fall back to the container CPU limit",
+			containerMeta: `{"DockerId":"container-id","Limits":{"CPU":1024}}`,
+			wantProcs:     1,
+			wantLog:       "maxprocs: Container not found in task metadata, using container CPU limit of 1024",
+		},
+		{
+			name:          "should fall back to the task CPU limit without container CPU limit",
+			containerMeta: `{"DockerId":"container-id","Limits":{}}`,
+			wantProcs:     2,
+			wantLog:       "maxprocs: Container not found in task metadata, using task CPU limit of 2",
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			runtime.GOMAXPROCS(4)
+
+			agent := tasktest.NewECSAgent(t).
+				WithContainerMetaEndpointJSON(tt.containerMeta).
+				WithTaskMetaEndpointJSON(`{"Containers":[{"DockerId":"other-id","Limits":{"CPU":3072}}],"Limits":{"CPU":2}}`).
+				Start().
+				SetMetaURIEnv()
+			defer agent.Close()
+
+			buf := new(bytes.Buffer)
+			logger := log.New(buf, "", 0)
+
+			undo, err := maxprocs.Set(maxprocs.WithLogger(logger.Printf))
+			require.NoError(t, err)
+			defer undo()
+
+			assert.Equal(t, tt.wantProcs, runtime.GOMAXPROCS(0))
+			assert.Contains(t, buf.String(), tt.wantLog)
+		})
+	}
 }
 
-func TestMaxProcs_Set_UndoResetsGOMAXPROCS(t *testing.T) {
-	initialProcs := 5
-	runtime.GOMAXPROCS(initialProcs)
+func TestMaxProcs_Set_ContainerLabelOverrides(t *testing.T) {
+	tests := []struct {
+		name            string
+		labels          string
+		opts            []config.Option
+		env             map[string]string
+		wantProcs       int
+		wantSource      maxprocs.Source
+		wantMemoryLimit int64
+		wantLog         string
+	}{
+		{
+			name:       "should set GOMAXPROCS to fixed procs label",
+			labels:     `{"gomaxecs.procs":"3"}`,
+			wantProcs:  3,
+			wantSource: maxprocs.SourceOverride,
+			wantLog:    `maxprocs: Overriding tuning with container label gomaxecs.procs="3"`,
+		},
+		{
+			name:       "should hold back headroom label from ECS limit",
+			labels:     `{"gomaxecs.headroom":"0.5"}`,
+			wantProcs:  2,
+			wantSource: maxprocs.SourceECS,
+		},
+		{
+			name:       "should override headroom option with headroom label",
+			labels:     `{"gomaxecs.headroom":"0.25"}`,
+			opts:       []config.Option{maxprocs.WithHeadroom(0.5)},
+			wantProcs:  3,
+			wantSource: maxprocs.SourceECS,
+		},
+		{
+			name:       "should ignore invalid label",
+			labels:     `{"gomaxecs.headroom":"2"}`,
+			opts:       []config.Option{maxprocs.WithHeadroom(0.5)},
+			wantProcs:  2,
+			wantSource: maxprocs.SourceECS,
+			wantLog:    `maxprocs: Ignoring container label gomaxecs.headroom: invalid headroom "2"`,
+		},
+		{
+			name:       "should ignore unknown label",
+			labels:     `{"gomaxecs.unknown":"1","other.procs":"3"}`,
+			wantProcs:  4,
+			wantSource: maxprocs.SourceECS,
+			wantLog:    `maxprocs: Ignoring container label gomaxecs.unknown: unknown key "unknown"`,
+		},
+		{
+			name:       "should leave GOMAXPROCS unchanged when disabled by label",
+			labels:     `{"gomaxecs.disable":"true","gomaxecs.procs":"3"}`,
+			wantProcs:  1,
+			wantSource: maxprocs.SourceNone,
+			wantLog:    "maxprocs: Setting GOMAXPROCS disabled by tuning override",
+		},
+		{
+			name:       "should honor GOMAXPROCS env over labels",
+			labels:     `{"gomaxecs.procs":"3"}`,
+			env:        map[string]string{"GOMAXPROCS": "1"},
+			wantProcs:  1,
+			wantSource: maxprocs.SourceEnv,
+		},
+		{
+			name:            "should set memory limit from memory limit ratio label",
+			labels:          `{"gomaxecs.memlimit-ratio":"0.5"}`,
+			wantProcs:       4,
+			wantSource:      maxprocs.SourceECS,
+			wantMemoryLimit: 256 << 20,
+		},
+		{
+			name:       "should honor GOMEMLIMIT env over memory limit ratio label",
+			labels:     `{"gomaxecs.memlimit-ratio":"0.5"}`,
+			env:        map[string]string{"GOMEMLIMIT": "1GiB"},
+			wantProcs:  4,
+			wantSource: maxprocs.SourceECS,
+			wantLog:    `maxprocs: Honoring GOMEMLIMIT="1GiB" as set in environment`,
+		},
+	}
 
-	taskCPU := 10
-	containerCPU := 0
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runtime.GOMAXPROCS(1)
+
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+
+			agent := tasktest.NewECSAgent(t).
+				WithContainerMetaEndpointJSON(fmt.Sprintf(
+					`{"DockerId":"container-id","Labels":%s,"Limits":{"CPU":4096,"Memory":512}}`, tt.labels)).
+				WithTaskMetaEndpointJSON(`{"Containers":[{"DockerId":"container-id","Limits":{"CPU":4096}}],"Limits":{"CPU":8}}`).
+				Start().
+				SetMetaURIEnv()
+			defer agent.Close()
+
+			buf := new(bytes.Buffer)
+			logger := log.New(buf, "", 0)
+
+			h, err := maxprocs.Apply(append(tt.opts, maxprocs.WithLogger(logger.Printf))...)
+			require.NoError(t, err)
+
+			res := h.Result()
+			assert.Equal(t, tt.wantProcs, runtime.GOMAXPROCS(0))
+			assert.Equal(t, tt.wantSource, res.Source)
+			assert.Equal(t, tt.wantMemoryLimit, res.MemoryLimit)
+			assert.Contains(t, buf.String(), tt.wantLog)
+
+			if tt.wantMemoryLimit > 0 {
+				assert.Equal(t, tt.wantMemoryLimit, debug.SetMemoryLimit(-1))
+			}
+
+			h.Reset()
+
+			assert.Equal(t, int64(math.MaxInt64), debug.SetMemoryLimit(-1))
+		})
+	}
+}
+
+func TestMaxProcs_Set_TaskTagOverrides(t *testing.T) {
+	tests := []struct {
+		name      string
+		labels    string
+		tags      string
+		opts      []config.Option
+		wantProcs int
+		wantLog   string
+	}{
+		{
+			name:      "should override option with container instance tag",
+			tags:      `"ContainerInstanceTags":{"gomaxecs.headroom":"0.25"}`,
+			opts:      []config.Option{maxprocs.WithTaskTags(), maxprocs.WithHeadroom(0.5)},
+			wantProcs: 3,
+			wantLog:   `maxprocs: Overriding tuning with container instance tag gomaxecs.headroom="0.25"`,
+		},
+		{
+			name:      "should override container instance tag with task tag",
+			tags:      `"ContainerInstanceTags":{"gomaxecs.headroom":"0.25"},"TaskTags":{"gomaxecs.headroom":"0.5"}`,
+			opts:      []config.Option{maxprocs.WithTaskTags()},
+			wantProcs: 2,
+			wantLog:   `maxprocs: Overriding tuning with task tag gomaxecs.headroom="0.5"`,
+		},
+		{
+			name:      "should override task tag with container label",
+			labels:    `{"gomaxecs.headroom":"0"}`,
+			tags:      `"TaskTags":{"gomaxecs.headroom":"0.5"}`,
+			opts:      []config.Option{maxprocs.WithTaskTags()},
+			wantProcs: 4,
+		},
+		{
+			name:      "should not read tags when task tags not enabled",
+			tags:      `"TaskTags":{"gomaxecs.headroom":"0.5"}`,
+			wantProcs: 4,
+		},
+		{
+			name: "should use incomplete tags",
+			tags: `"TaskTags":{"gomaxecs.headroom":"0.5"},"Errors":[{"ErrorField":"ContainerInstanceTags",` +
+				`"ErrorCode":"AccessDeniedException","ErrorMessage":"not authorized"}]`,
+			opts:      []config.Option{maxprocs.WithTaskTags()},
+			wantProcs: 2,
+			wantLog: "maxprocs: Using incomplete task tags: " +
+				"task tags incomplete: ContainerInstanceTags: AccessDeniedException: not authorized",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runtime.GOMAXPROCS(1)
+
+			labels := tt.labels
+			if labels == "" {
+				labels = "{}"
+			}
+
+			agent := tasktest.NewECSAgent(t).
+				WithContainerMetaEndpointJSON(fmt.Sprintf(
+					`{"DockerId":"container-id","Labels":%s,"Limits":{"CPU":4096}}`, labels)).
+				WithTaskMetaEndpointJSON(`{"Containers":[{"DockerId":"container-id","Limits":{"CPU":4096}}],"Limits":{"CPU":8}}`).
+				WithTaskWithTagsMetaEndpointJSON(fmt.Sprintf(
+					`{"Containers":[{"DockerId":"container-id","Limits":{"CPU":4096}}],"Limits":{"CPU":8},%s}`, tt.tags)).
+				Start().
+				SetMetaURIEnv()
+			defer agent.Close()
 
+			buf := new(bytes.Buffer)
+			logger := log.New(buf, "", 0)
+
+			undo, err := maxprocs.Set(append(tt.opts, maxprocs.WithLogger(logger.Printf))...)
+			require.NoError(t, err)
+			defer undo()
+
+			assert.Equal(t, tt.wantProcs, runtime.GOMAXPROCS(0))
+			assert.Contains(t, buf.String(), tt.wantLog)
+		})
+	}
+}
+
+func TestMaxProcs_Set_FallsBackToTaskMetadataWhenTagsUnavailable(t *testing.T) {
 	agent := tasktest.NewECSAgent(t).
 		WithContainerMetaEndpoint(containerCPU).
 		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		WithTaskWithTagsMetaEndpointNotFound().
 		Start().
 		SetMetaURIEnv()
 	defer agent.Close()
@@ -142,22 +564,1084 @@ func TestMaxProcs_Set_UndoResetsGOMAXPROCS(t *testing.T) {
 	buf := new(bytes.Buffer)
 	logger := log.New(buf, "", 0)
 
-	undo, _ := maxprocs.Set(maxprocs.WithLogger(logger.Printf))
+	undo, err := maxprocs.Set(maxprocs.WithTaskTags(), maxprocs.WithLogger(logger.Printf))
+	require.NoError(t, err)
+	defer undo()
+
+	assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+	assert.Contains(t, buf.String(),
+		"maxprocs: Falling back to task metadata without tags: task tags unavailable: request failed, status code: 404")
+}
+
+func TestMaxProcs_Set_DryRunReportsWithoutChanges(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(`{"DockerId":"container-id","Limits":{"CPU":2048,"Memory":512}}`).
+		WithTaskMetaEndpointJSON(`{"Containers":[{"DockerId":"container-id","Limits":{"CPU":2048}}],"Limits":{"CPU":8}}`).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
 
-	assert.Equal(t, taskCPU, runtime.GOMAXPROCS(0)) // GOMAXPROCS should be set to taskCPU
+	h, err := maxprocs.Apply(maxprocs.WithDryRun(), maxprocs.WithMemoryLimitRatio(0.5), maxprocs.WithLogger(logger.Printf))
+	require.NoError(t, err)
 
-	undo() // reset GOMAXPROCS
+	res := h.Result()
+	assert.True(t, res.DryRun)
+	assert.Equal(t, 2, res.Procs)
+	assert.Equal(t, maxprocs.SourceECS, res.Source)
+	assert.Equal(t, int64(256<<20), res.MemoryLimit)
+	assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+	assert.Equal(t, int64(math.MaxInt64), debug.SetMemoryLimit(-1))
+	assert.Contains(t, buf.String(), fmt.Sprintf(
+		"maxprocs: Dry run, would update GOMAXPROCS=2 (currently 1) and memory limit=%d (currently %d)",
+		256<<20, int64(math.MaxInt64)))
 
-	assert.Equal(t, initialProcs, runtime.GOMAXPROCS(0)) // GOMAXPROCS should be reset to initialProcs
+	h.Reset()
 
-	assert.Contains(t, buf.String(), fmt.Sprintf("maxprocs: Resetting GOMAXPROCS to %v", initialProcs))
+	assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+	assert.Contains(t, buf.String(), "maxprocs: No GOMAXPROCS change to reset")
 }
 
-func TestMaxProcs_IsECS_ReturnsTrueIfDetectedECSEnvironment(t *testing.T) {
-	t.Setenv(metaURIEnv, "mock-ecs-metadata-uri")
-	assert.True(t, maxprocs.IsECS())
+func TestMaxProcs_Handle_Refresh_ResetsStaleMemoryLimit(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(`{"DockerId":"container-id","Limits":{"CPU":2048,"Memory":512}}`).
+		WithTaskMetaEndpointJSON(`{"Containers":[{"DockerId":"container-id","Limits":{"CPU":2048}}],"Limits":{"CPU":8}}`).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+
+	h, err := maxprocs.Apply(maxprocs.WithMemoryLimitRatio(0.5), maxprocs.WithLogger(logger.Printf))
+	require.NoError(t, err)
+	defer h.Reset()
+
+	assert.Equal(t, int64(256<<20), h.Result().MemoryLimit)
+	assert.Equal(t, int64(256<<20), debug.SetMemoryLimit(-1))
+
+	t.Setenv("GOMEMLIMIT", "1GiB")
+
+	res, err := h.Refresh()
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, res.Procs)
+	assert.Zero(t, res.MemoryLimit)
+	assert.Equal(t, int64(math.MaxInt64), debug.SetMemoryLimit(-1))
+	assert.Contains(t, buf.String(), fmt.Sprintf("maxprocs: Resetting memory limit to %d", int64(math.MaxInt64)))
+
+	h.Reset()
+
+	assert.Equal(t, int64(math.MaxInt64), debug.SetMemoryLimit(-1))
 }
 
-func TestMaxProcs_IsECS_ReturnsFalseIfNotDetectedECSEnvironment(t *testing.T) {
-	assert.False(t, maxprocs.IsECS())
+func TestMaxProcs_Set_LogsInvalidTuningOptions(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(2048).
+		WithTaskMetaEndpoint(2048, 8).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+
+	h, err := maxprocs.Apply(
+		maxprocs.WithHeadroom(2),
+		maxprocs.WithMemoryLimitRatio(-0.5),
+		maxprocs.WithLogger(logger.Printf),
+	)
+	require.NoError(t, err)
+	defer h.Reset()
+
+	assert.Equal(t, 2, h.Result().Procs)
+	assert.Contains(t, buf.String(),
+		"maxprocs: Ignoring invalid option: invalid headroom 2: must be a number from 0 up to but excluding 1")
+	assert.Contains(t, buf.String(),
+		"maxprocs: Ignoring invalid option: invalid memlimit-ratio -0.5: must be a number from 0 to 1")
+}
+
+func TestMaxProcs_Set_AppliesAfterDryRun(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(2048).
+		WithTaskMetaEndpoint(2048, 4).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	undoDryRun, err := maxprocs.Set(maxprocs.WithDryRun())
+	require.NoError(t, err)
+	defer undoDryRun()
+
+	assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+
+	_, active := maxprocs.Status()
+	assert.False(t, active)
+
+	undo, err := maxprocs.Set()
+	require.NoError(t, err)
+
+	assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+
+	undo()
+
+	assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+}
+
+func TestMaxProcs_Set_StrictPanicsWhenFailToResolve(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointInternalServerError().
+		WithTaskMetaEndpointInternalServerError().
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	defer func() {
+		err, ok := recover().(error)
+		require.True(t, ok)
+		require.ErrorIs(t, err, maxprocs.ErrStrict)
+		assert.ErrorContains(t, err, "failed to set GOMAXPROCS: failed to get ECS container meta")
+		assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+	}()
+
+	_, _ = maxprocs.Set(maxprocs.WithStrict())
+
+	t.Fatal("expected strict mode to panic")
+}
+
+func TestMaxProcs_Set_EnvClampFailsWhenFailToResolve(t *testing.T) {
+	runtime.GOMAXPROCS(3)
+	t.Setenv("GOMAXPROCS", "3")
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointInternalServerError().
+		WithTaskMetaEndpointInternalServerError().
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	undo, err := maxprocs.Set(maxprocs.WithEnvPolicy(maxprocs.EnvClamp))
+	defer undo()
+
+	require.ErrorContains(t, err, "failed to set GOMAXPROCS: failed to get ECS")
+	assert.Equal(t, 3, runtime.GOMAXPROCS(0))
+
+	defer func() {
+		err, ok := recover().(error)
+		require.True(t, ok)
+		require.ErrorIs(t, err, maxprocs.ErrStrict)
+		assert.Equal(t, 3, runtime.GOMAXPROCS(0))
+	}()
+
+	_, _ = maxprocs.Set(maxprocs.WithEnvPolicy(maxprocs.EnvClamp), maxprocs.WithStrict())
+
+	t.Fatal("expected strict mode to panic")
+}
+
+func TestMaxProcs_Set_StrictDoesNotPanicWhenNotECS(t *testing.T) {
+	t.Setenv(metaURIEnv, "")
+
+	_, err := maxprocs.Set(maxprocs.WithStrict())
+	assert.Error(t, err)
+}
+
+func TestMaxProcs_Set_UndoLogsNoChangesWhenHonorsGOMAXPROCSEnv(t *testing.T) {
+	t.Setenv("GOMAXPROCS", "4")
+
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+
+	undo, _ := maxprocs.Set(maxprocs.WithLogger(logger.Printf))
+
+	undo()
+
+	assert.Contains(t, buf.String(), "maxprocs: No GOMAXPROCS change to reset")
+}
+
+func TestMaxProcs_SetContext_ReturnsErrCanceledWhenContextDone(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		WithLatency(time.Second).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	undo, err := maxprocs.SetContext(ctx, maxprocs.WithLogger(logger.Printf))
+	defer undo()
+
+	require.ErrorIs(t, err, maxprocs.ErrCanceled)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+	assert.Contains(t, buf.String(), "maxprocs: Canceled setting GOMAXPROCS: context deadline exceeded")
+	assert.NotContains(t, buf.String(), "maxprocs: Failed to set GOMAXPROCS")
+}
+
+func TestMaxProcs_SetContext_RetriesFailedRequests(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpointUnavailable(2, containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	undo, err := maxprocs.SetContext(context.Background(), maxprocs.WithRetry(2, time.Millisecond))
+	require.NoError(t, err)
+	defer undo()
+
+	assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+}
+
+func TestMaxProcs_Set_FailsWhenMetadataExceedsMaxResponseSize(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	undo, err := maxprocs.Set(maxprocs.WithMaxResponseSize(16))
+	defer undo()
+
+	require.ErrorIs(t, err, maxprocs.ErrResponseTooLarge)
+	require.ErrorContains(t, err, "metadata response too large: exceeds max size of 16 bytes")
+	assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+}
+
+func TestMaxProcs_Set_FailsWhenMetadataMalformed(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpointJSON(`{"Containers":[],"Limits":{"CPU":1}}}`).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	undo, err := maxprocs.Set()
+	defer undo()
+
+	require.ErrorIs(t, err, maxprocs.ErrMalformedMetadata)
+	require.ErrorContains(t, err, "unmarshal failed: unexpected data after top-level value")
+	assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+}
+
+func TestMaxProcs_Apply_ReturnsSameHandleWhenCalledConcurrently(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	calls := 10
+	handles := make([]*maxprocs.Handle, calls)
+
+	var wg sync.WaitGroup
+	for i := range calls {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			h, err := maxprocs.Apply()
+			assert.NoError(t, err)
+
+			handles[i] = h
+		}()
+	}
+
+	wg.Wait()
+
+	for _, h := range handles {
+		assert.Same(t, handles[0], h)
+	}
+
+	for _, h := range handles {
+		_, ok := maxprocs.Status()
+		assert.True(t, ok)
+
+		h.Reset()
+	}
+
+	_, ok := maxprocs.Status()
+	assert.False(t, ok)
+}
+
+func TestMaxProcs_Apply_DoesNotBlockReadersWhileResolving(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		WithLatency(200 * time.Millisecond).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	first := make(chan *maxprocs.Handle)
+
+	go func() {
+		h, err := maxprocs.Apply()
+		assert.NoError(t, err)
+
+		first <- h
+	}()
+
+	time.Sleep(20 * time.Millisecond)
+
+	start := time.Now()
+	_, ok := maxprocs.Status()
+	_ = maxprocs.ReadStats()
+	_ = maxprocs.Inspect()
+
+	assert.False(t, ok)
+	assert.Less(t, time.Since(start), 100*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := maxprocs.ApplyContext(ctx)
+	require.ErrorIs(t, err, maxprocs.ErrCanceled)
+
+	second, err := maxprocs.Apply()
+	require.NoError(t, err)
+
+	h := <-first
+	assert.Same(t, h, second)
+
+	second.Reset()
+	h.Reset()
+}
+
+func TestMaxProcs_Set_ResetsOnceReleasedByEveryCaller(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	undoFirst, err := maxprocs.Set()
+	require.NoError(t, err)
+
+	undoSecond, err := maxprocs.Set()
+	require.NoError(t, err)
+
+	undoSecond()
+	undoSecond()
+
+	_, ok := maxprocs.Status()
+	assert.True(t, ok)
+	assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+
+	undoFirst()
+
+	_, ok = maxprocs.Status()
+	assert.False(t, ok)
+}
+
+func TestMaxProcs_Handle_Refresh_ResetsGOMAXPROCSNoLongerSet(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(2048).
+		WithTaskMetaEndpoint(2048, 4).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	h, err := maxprocs.Apply()
+	require.NoError(t, err)
+	require.Equal(t, 2, runtime.GOMAXPROCS(0))
+
+	t.Setenv("GOMAXPROCS", "3")
+
+	res, err := h.Refresh()
+	require.NoError(t, err)
+
+	assert.Equal(t, maxprocs.SourceEnv, res.Source)
+	assert.Equal(t, 1, res.Procs)
+	assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+
+	h.Reset()
+
+	assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+}
+
+func TestMaxProcs_Handle_Refresh_FailsOnceReleasedWithoutChange(t *testing.T) {
+	t.Setenv("GOMAXPROCS", "3")
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(2048).
+		WithTaskMetaEndpoint(2048, 4).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	h, err := maxprocs.Apply()
+	require.NoError(t, err)
+	require.Equal(t, maxprocs.SourceEnv, h.Result().Source)
+
+	h.Reset()
+
+	os.Unsetenv("GOMAXPROCS")
+	procs := runtime.GOMAXPROCS(0)
+
+	_, err = h.Refresh()
+	require.EqualError(t, err, "handle has been reset")
+
+	_, active := maxprocs.Status()
+	assert.False(t, active)
+	assert.Equal(t, procs, runtime.GOMAXPROCS(0))
+}
+
+func TestMaxProcs_Handle_Refresh_KeepsResultOnFailure(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+
+	h, err := maxprocs.Apply(maxprocs.WithLogger(logger.Printf))
+	require.NoError(t, err)
+
+	want := h.Result()
+
+	agent.Close()
+
+	res, err := h.Refresh()
+	require.Error(t, err)
+	assert.Equal(t, want, res)
+	assert.Equal(t, want, h.Result())
+	assert.Equal(t, maxprocs.SourceNone, maxprocs.ReadStats().Last.Source)
+
+	h.Reset()
+
+	assert.Contains(t, buf.String(), "maxprocs: Resetting GOMAXPROCS")
+}
+
+func TestMaxProcs_Apply_KeepsPreviousWhenCalledRepeatedly(t *testing.T) {
+	initialProcs := 5
+	runtime.GOMAXPROCS(initialProcs)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	first, err := maxprocs.Apply()
+	require.NoError(t, err)
+	defer first.Reset()
+
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+
+	second, err := maxprocs.Apply(maxprocs.WithLogger(logger.Printf))
+	require.NoError(t, err)
+	defer second.Reset()
+
+	assert.Same(t, first, second)
+	assert.Equal(t, initialProcs, second.Result().Previous)
+	assert.Contains(t, buf.String(), "maxprocs: GOMAXPROCS=2 already set by "+
+		"github.com/rdforte/gomaxecs/maxprocs_test.TestMaxProcs_Apply_KeepsPreviousWhenCalledRepeatedly")
+}
+
+func TestMaxProcs_Handle_Result(t *testing.T) {
+	initialProcs := 5
+	runtime.GOMAXPROCS(initialProcs)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	h, err := maxprocs.Apply()
+	require.NoError(t, err)
+	defer h.Reset()
+
+	res := h.Result()
+	assert.Positive(t, res.Duration)
+
+	want := maxprocs.Result{
+		Procs:        2,
+		Previous:     initialProcs,
+		Source:       maxprocs.SourceECS,
+		SetBy:        "github.com/rdforte/gomaxecs/maxprocs_test.TestMaxProcs_Handle_Result",
+		ContainerCPU: containerCPU,
+		TaskCPU:      taskCPU,
+		Identity:     maxprocs.Identity{ContainerID: "container-id"},
+		Duration:     res.Duration,
+
+		PreviousMemoryLimit: math.MaxInt64,
+	}
+	assert.Equal(t, want, res)
+	assert.Equal(t, 2, h.Current())
+}
+
+func TestMaxProcs_Handle_Refresh(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	h, err := maxprocs.Apply()
+	require.NoError(t, err)
+
+	runtime.GOMAXPROCS(7)
+
+	res, err := h.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Procs)
+	assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+
+	h.Reset()
+
+	_, err = h.Refresh()
+	assert.ErrorContains(t, err, "handle has been reset")
+}
+
+func TestMaxProcs_Handle_Override(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+
+	h, err := maxprocs.Apply(maxprocs.WithLogger(logger.Printf))
+	require.NoError(t, err)
+
+	active, ok := maxprocs.Active()
+	assert.True(t, ok)
+	assert.Same(t, h, active)
+
+	_, err = h.Override(3)
+	require.ErrorIs(t, err, maxprocs.ErrOutOfBounds)
+	require.EqualError(t, err, "GOMAXPROCS override out of bounds: 3 is not from 1 to 2")
+	_, err = h.Override(0)
+	require.ErrorIs(t, err, maxprocs.ErrOutOfBounds)
+
+	res, err := h.Override(1)
+	require.NoError(t, err)
+	assert.Equal(t, 1, res.Procs)
+	assert.Equal(t, maxprocs.SourceAdmin, res.Source)
+	assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+	assert.Contains(t, buf.String(), "maxprocs: Overriding GOMAXPROCS=1 (was 2)")
+	assert.Equal(t, 1, maxprocs.ReadStats().Last.Procs)
+	assert.Equal(t, maxprocs.SourceAdmin, maxprocs.ReadStats().Last.Source)
+	assert.Equal(t, 1, maxprocs.Inspect().Result.Procs)
+
+	res, err = h.Revert()
+	require.NoError(t, err)
+	assert.Equal(t, 2, res.Procs)
+	assert.Equal(t, maxprocs.SourceECS, res.Source)
+	assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+	assert.Contains(t, buf.String(), "maxprocs: Reverting GOMAXPROCS override to 2 (was 1)")
+	assert.Equal(t, 2, maxprocs.ReadStats().Last.Procs)
+	assert.Equal(t, maxprocs.SourceECS, maxprocs.Inspect().Result.Source)
+
+	_, err = h.Override(1)
+	require.NoError(t, err)
+
+	res, err = h.Refresh()
+	require.NoError(t, err)
+	assert.Equal(t, maxprocs.SourceECS, res.Source)
+	assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+
+	_, err = h.Override(1)
+	require.NoError(t, err)
+
+	h.Reset()
+	assert.Contains(t, buf.String(), "maxprocs: Resetting GOMAXPROCS")
+
+	_, ok = maxprocs.Active()
+	assert.False(t, ok)
+
+	_, err = h.Override(1)
+	require.ErrorContains(t, err, "handle has been reset")
+	_, err = h.Revert()
+	require.ErrorContains(t, err, "handle has been reset")
+}
+
+func TestMaxProcs_Handle_Override_FailsWithoutECSLimit(t *testing.T) {
+	t.Setenv("GOMAXPROCS", "4")
+
+	h, err := maxprocs.Apply()
+	require.NoError(t, err)
+	defer h.Reset()
+
+	_, err = h.Override(1)
+	require.ErrorIs(t, err, maxprocs.ErrOutOfBounds)
+	assert.ErrorContains(t, err, "no ECS CPU limit to bound GOMAXPROCS")
+}
+
+func TestMaxProcs_Handle_Override_FailsInDryRun(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	h, err := maxprocs.Apply(maxprocs.WithDryRun())
+	require.NoError(t, err)
+	defer h.Reset()
+
+	_, err = h.Override(1)
+	assert.ErrorContains(t, err, "GOMAXPROCS can not be overridden in dry run")
+}
+
+func TestMaxProcs_Watchdog_DetectsDrift(t *testing.T) {
+	tests := []struct {
+		name      string
+		reapply   bool
+		wantProcs int
+		wantLog   string
+	}{
+		{
+			name:      "should log drift",
+			wantProcs: 1,
+			wantLog:   "maxprocs: GOMAXPROCS changed by other code to 1 (set to 2)",
+		},
+		{
+			name:      "should reapply GOMAXPROCS on drift",
+			reapply:   true,
+			wantProcs: 2,
+			wantLog:   "maxprocs: Reapplying GOMAXPROCS=2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agent := tasktest.NewECSAgent(t).
+				WithContainerMetaEndpoint(containerCPU).
+				WithTaskMetaEndpoint(containerCPU, taskCPU).
+				Start().
+				SetMetaURIEnv()
+			defer agent.Close()
+
+			buf := new(bytes.Buffer)
+			logger := log.New(buf, "", 0)
+			drifts := maxprocs.ReadStats().Drifts
+
+			h, err := maxprocs.Apply(maxprocs.WithLogger(logger.Printf), maxprocs.WithWatchdog(time.Millisecond, tt.reapply))
+			require.NoError(t, err)
+
+			runtime.GOMAXPROCS(1)
+
+			assert.Eventually(t, func() bool {
+				return maxprocs.ReadStats().Drifts > drifts
+			}, time.Second, time.Millisecond)
+			assert.Equal(t, tt.wantProcs, runtime.GOMAXPROCS(0))
+
+			h.Reset()
+
+			assert.Contains(t, buf.String(), tt.wantLog)
+		})
+	}
+}
+
+func TestMaxProcs_Watchdog_CountsPersistentDriftOnce(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+	drifts := maxprocs.ReadStats().Drifts
+
+	h, err := maxprocs.Apply(maxprocs.WithLogger(logger.Printf), maxprocs.WithWatchdog(time.Millisecond, false))
+	require.NoError(t, err)
+
+	runtime.GOMAXPROCS(1)
+	assert.Eventually(t, func() bool {
+		return maxprocs.ReadStats().Drifts == drifts+1
+	}, time.Second, time.Millisecond)
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, drifts+1, maxprocs.ReadStats().Drifts)
+
+	runtime.GOMAXPROCS(3)
+	assert.Eventually(t, func() bool {
+		return maxprocs.ReadStats().Drifts == drifts+2
+	}, time.Second, time.Millisecond)
+
+	h.Reset()
+
+	assert.Equal(t, 1, strings.Count(buf.String(), "maxprocs: GOMAXPROCS changed by other code to 1 (set to 2)"))
+	assert.Equal(t, 1, strings.Count(buf.String(), "maxprocs: GOMAXPROCS changed by other code to 3 (set to 2)"))
+}
+
+func TestMaxProcs_Watchdog_StopsOnReset(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	h, err := maxprocs.Apply(maxprocs.WithWatchdog(time.Millisecond, true))
+	require.NoError(t, err)
+
+	h.Reset()
+	runtime.GOMAXPROCS(1)
+	drifts := maxprocs.ReadStats().Drifts
+
+	time.Sleep(10 * time.Millisecond)
+
+	assert.Equal(t, drifts, maxprocs.ReadStats().Drifts)
+	assert.Equal(t, 1, runtime.GOMAXPROCS(0))
+}
+
+func TestMaxProcs_Status(t *testing.T) {
+	_, ok := maxprocs.Status()
+	assert.False(t, ok)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	h, err := maxprocs.Apply()
+	require.NoError(t, err)
+
+	status, ok := maxprocs.Status()
+	assert.True(t, ok)
+	assert.Equal(t, h.Result(), status)
+
+	h.Reset()
+
+	_, ok = maxprocs.Status()
+	assert.False(t, ok)
+}
+
+func TestMaxProcs_ReadStats_RecordsAttempts(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpointUnavailable(1, containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	before := maxprocs.ReadStats()
+
+	_, err := maxprocs.Set()
+	require.Error(t, err)
+
+	failed := maxprocs.ReadStats()
+	assert.Equal(t, before.Attempts+1, failed.Attempts)
+	assert.Equal(t, before.Errors+1, failed.Errors)
+	assert.Equal(t, err, failed.LastError)
+	assert.Equal(t, maxprocs.SourceNone, failed.Last.Source)
+
+	undo, err := maxprocs.Set()
+	require.NoError(t, err)
+	defer undo()
+
+	succeeded := maxprocs.ReadStats()
+	assert.Equal(t, failed.Attempts+1, succeeded.Attempts)
+	assert.Equal(t, failed.Errors, succeeded.Errors)
+	assert.Equal(t, maxprocs.SourceECS, succeeded.Last.Source)
+}
+
+func TestMaxProcs_Set_WritesEMF(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(`{"DockerId":"container-id","Name":"app","Limits":{"CPU":2048}}`).
+		WithTaskMetaEndpointJSON(`{"Cluster":"arn:aws:ecs:us-east-1:123456789012:cluster/default",` +
+			`"ServiceName":"api","Family":"service","Containers":[{"DockerId":"container-id","Limits":{"CPU":2048}}],` +
+			`"Limits":{"CPU":4,"Memory":8192}}`).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	buf := new(bytes.Buffer)
+
+	undo, err := maxprocs.Set(maxprocs.WithEMF(buf))
+	require.NoError(t, err)
+	defer undo()
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	aws, ok := doc["_aws"].(map[string]any)
+	require.True(t, ok)
+	assert.Positive(t, aws["Timestamp"])
+
+	delete(aws, "Timestamp")
+
+	wantDoc := map[string]any{
+		"_aws": map[string]any{
+			"CloudWatchMetrics": []any{map[string]any{
+				"Namespace":  "gomaxecs",
+				"Dimensions": []any{[]any{"ClusterName", "ServiceName", "TaskFamily"}},
+				"Metrics": []any{
+					map[string]any{"Name": "GOMAXPROCS", "Unit": "Count"},
+					map[string]any{"Name": "ContainerCPU", "Unit": "None"},
+					map[string]any{"Name": "TaskCPU", "Unit": "None"},
+					map[string]any{"Name": "TaskMemory", "Unit": "Megabytes"},
+				},
+			}},
+		},
+		"ClusterName":   "default",
+		"ServiceName":   "api",
+		"TaskFamily":    "service",
+		"GOMAXPROCS":    float64(2),
+		"ContainerCPU":  float64(2),
+		"TaskCPU":       float64(4),
+		"TaskMemory":    float64(8192),
+		"Source":        "ecs",
+		"DryRun":        "false",
+		"ContainerName": "app",
+	}
+	assert.Equal(t, wantDoc, doc)
+}
+
+func TestMaxProcs_Set_WritesEMFDimensionsWhenSetInEnvironment(t *testing.T) {
+	maxprocs.ResetTaskMetadata()
+	defer maxprocs.ResetTaskMetadata()
+
+	t.Setenv("GOMAXPROCS", "2")
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(`{"DockerId":"container-id","Name":"app","Limits":{"CPU":2048}}`).
+		WithTaskMetaEndpointJSON(`{"Cluster":"default","ServiceName":"api","Family":"service","Containers":[]}`).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	buf := new(bytes.Buffer)
+
+	undo, err := maxprocs.Set(maxprocs.WithEMF(buf))
+	require.NoError(t, err)
+	defer undo()
+
+	var doc map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &doc))
+
+	assert.Equal(t, "default", doc["ClusterName"])
+	assert.Equal(t, "api", doc["ServiceName"])
+	assert.Equal(t, "service", doc["TaskFamily"])
+	assert.Equal(t, "app", doc["ContainerName"])
+	assert.Equal(t, "env", doc["Source"])
+}
+
+func TestMaxProcs_Set_DoesNotWriteEMFOnFailure(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpointUnavailable(1, containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	buf := new(bytes.Buffer)
+
+	_, err := maxprocs.Set(maxprocs.WithEMF(buf))
+	require.Error(t, err)
+	assert.Empty(t, buf.String())
+}
+
+func TestMaxProcs_Inspect(t *testing.T) {
+	containerMeta := `{"DockerId":"container-id","Name":"app","Limits":{"CPU":2048}}`
+	taskMeta := `{"Family":"service","Containers":[{"DockerId":"container-id","Limits":{"CPU":2048}}],"Limits":{"CPU":4}}`
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(containerMeta).
+		WithTaskMetaEndpointJSON(taskMeta).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	undo, err := maxprocs.Set(maxprocs.WithEnvPolicy(maxprocs.EnvClamp), maxprocs.WithRetry(2, time.Millisecond))
+	require.NoError(t, err)
+	defer undo()
+
+	in := maxprocs.Inspect()
+
+	assert.Equal(t, maxprocs.ReadStats().Last, in.Result)
+	assert.Empty(t, in.Error)
+	assert.True(t, in.Active)
+	assert.Equal(t, 2, in.GOMAXPROCS)
+	assert.Equal(t, runtime.NumCPU(), in.NumCPU)
+	assert.Equal(t, debug.SetMemoryLimit(-1), in.MemoryLimit)
+	assert.JSONEq(t, containerMeta, string(in.ContainerMetadata))
+	assert.JSONEq(t, taskMeta, string(in.TaskMetadata))
+
+	assert.Equal(t, "clamp", in.Options.EnvPolicy)
+	assert.Equal(t, "override", in.Options.RuntimePolicy)
+	assert.Equal(t, 2, in.Options.MaxRetries)
+	assert.Equal(t, time.Millisecond, in.Options.RetryBackoff)
+
+	require.NotEmpty(t, in.Trace)
+	last := in.Trace[len(in.Trace)-1]
+	assert.Equal(t, slog.LevelInfo, last.Level)
+	assert.Equal(t, "maxprocs: Updated GOMAXPROCS=2", last.Message)
+}
+
+func TestMaxProcs_Inspect_RecordsFailure(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpointUnavailable(1, containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	_, err := maxprocs.Set()
+	require.Error(t, err)
+
+	in := maxprocs.Inspect()
+
+	assert.False(t, in.Active)
+	assert.Equal(t, err.Error(), in.Error)
+	assert.Nil(t, in.TaskMetadata)
+	require.NotEmpty(t, in.Trace)
+	assert.Equal(t, slog.LevelError, in.Trace[len(in.Trace)-1].Level)
+	assert.Contains(t, in.Trace[len(in.Trace)-1].Message, "maxprocs: Failed to set GOMAXPROCS")
+}
+
+func TestMaxProcs_Inspect_RecordsOnlyMetadataOfLastAttempt(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+
+	h, err := maxprocs.Apply()
+	require.NoError(t, err)
+	defer h.Reset()
+
+	require.NotNil(t, maxprocs.Inspect().ContainerMetadata)
+
+	agent.Close()
+
+	_, err = h.Refresh()
+	require.Error(t, err)
+
+	in := maxprocs.Inspect()
+
+	assert.Equal(t, err.Error(), in.Error)
+	assert.Nil(t, in.ContainerMetadata)
+	assert.Nil(t, in.TaskMetadata)
+}
+
+func TestMaxProcs_TaskMetadata_FetchesAndCachesIdentity(t *testing.T) {
+	maxprocs.ResetTaskMetadata()
+	defer maxprocs.ResetTaskMetadata()
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(`{"DockerId":"container-id","Name":"app","Limits":{"CPU":1024}}`).
+		WithTaskMetaEndpointJSON(`{"Family":"service","Revision":"3","Containers":[]}`).
+		Start().
+		SetMetaURIEnv()
+
+	want := maxprocs.Identity{Family: "service", Revision: "3", ContainerID: "container-id", ContainerName: "app"}
+
+	id, err := maxprocs.TaskMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, want, id)
+
+	agent.Close()
+
+	id, err = maxprocs.TaskMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, want, id)
+}
+
+func TestMaxProcs_TaskMetadata_CachesIdentityFromSet(t *testing.T) {
+	maxprocs.ResetTaskMetadata()
+	defer maxprocs.ResetTaskMetadata()
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(`{"DockerId":"container-id","Name":"app","Limits":{"CPU":1024}}`).
+		WithTaskMetaEndpointJSON(`{"Family":"service","Containers":[{"DockerId":"container-id"}],"Limits":{"CPU":2}}`).
+		Start().
+		SetMetaURIEnv()
+
+	undo, err := maxprocs.Set()
+	require.NoError(t, err)
+	defer undo()
+
+	agent.Close()
+
+	id, err := maxprocs.TaskMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, maxprocs.Identity{Family: "service", ContainerID: "container-id", ContainerName: "app"}, id)
+}
+
+func TestMaxProcs_TaskMetadata_DoesNotCacheErrors(t *testing.T) {
+	maxprocs.ResetTaskMetadata()
+	defer maxprocs.ResetTaskMetadata()
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpointUnavailable(1, containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	_, err := maxprocs.TaskMetadata(context.Background())
+	require.Error(t, err)
+
+	id, err := maxprocs.TaskMetadata(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "container-id", id.ContainerID)
+}
+
+func TestMaxProcs_IsECS_ReturnsTrueIfDetectedECSEnvironment(t *testing.T) {
+	t.Setenv(metaURIEnv, "mock-ecs-metadata-uri")
+	assert.True(t, maxprocs.IsECS())
+}
+
+func TestMaxProcs_IsECS_ReturnsFalseIfNotDetectedECSEnvironment(t *testing.T) {
+	assert.False(t, maxprocs.IsECS())
+}
+
+func TestMaxProcs_Result_Units(t *testing.T) {
+	res := maxprocs.Result{ContainerCPU: 1536, TaskCPU: 2, ContainerMemory: 512, TaskMemory: 1024}
+
+	assert.InDelta(t, 1.5, res.ContainerVCPUs(), 0)
+	assert.Equal(t, int64(512<<20), res.ContainerMemoryBytes())
+	assert.Equal(t, int64(1<<30), res.TaskMemoryBytes())
+	assert.Zero(t, maxprocs.Result{}.ContainerVCPUs())
 }
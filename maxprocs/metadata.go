@@ -0,0 +1,57 @@
+package maxprocs
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+	ecstask "github.com/rdforte/gomaxecs/internal/task"
+)
+
+// metadata caches the identity of the ECS task and container, which does not change
+// over the lifetime of the task.
+//
+//nolint:gochecknoglobals // the task identity is process wide state.
+var metadata struct {
+	mu       sync.Mutex
+	identity Identity
+	ok       bool
+}
+
+// TaskMetadata returns the identity of the ECS task and container. The identity is
+// fetched from the ECS metadata once and cached for the lifetime of the process,
+// including when fetched while setting GOMAXPROCS, so the options only apply to the
+// first successful fetch. Errors are not cached and the next call tries again.
+func TaskMetadata(ctx context.Context, opts ...config.Option) (Identity, error) {
+	metadata.mu.Lock()
+	defer metadata.mu.Unlock()
+
+	if metadata.ok {
+		return metadata.identity, nil
+	}
+
+	id, err := ecstask.New(config.New(opts...)).GetIdentity(ctx)
+	if err != nil {
+		return Identity{}, err
+	}
+
+	metadata.identity, metadata.ok = id, true
+
+	return id, nil
+}
+
+// cacheIdentity caches the identity fetched while setting GOMAXPROCS.
+func cacheIdentity(id Identity) {
+	metadata.mu.Lock()
+	defer metadata.mu.Unlock()
+
+	metadata.identity, metadata.ok = id, true
+}
+
+// cachedIdentity returns the cached identity, or false if not yet fetched.
+func cachedIdentity() (Identity, bool) {
+	metadata.mu.Lock()
+	defer metadata.mu.Unlock()
+
+	return metadata.identity, metadata.ok
+}
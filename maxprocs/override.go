@@ -0,0 +1,115 @@
+package maxprocs
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+)
+
+// ErrOutOfBounds is returned when overriding GOMAXPROCS with a value outside of the
+// bounds derived from the ECS CPU limit.
+var ErrOutOfBounds = errors.New("GOMAXPROCS override out of bounds")
+
+var errDryRunOverride = errors.New("GOMAXPROCS can not be overridden in dry run")
+
+// Active returns the Handle of the active Set and true, or false if GOMAXPROCS has not
+// been set or has since been reset.
+func Active() (*Handle, bool) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return state.handle, state.handle != nil
+}
+
+// Override sets GOMAXPROCS to procs, such as during an incident, until reverted, refreshed
+// or reset. procs must be from 1 up to the ECS CPU limit rounded up, that is the container
+// CPU limit, falling back to the task CPU limit, otherwise an error wrapping ErrOutOfBounds
+// is returned. The Result reports the overridden value with SourceAdmin.
+func (h *Handle) Override(procs int) (Result, error) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if h.reset {
+		return h.result, errHandleReset
+	}
+
+	if h.cfg.DryRun {
+		return h.result, errDryRunOverride
+	}
+
+	res := h.result
+	if h.overridden {
+		res = h.computed
+	}
+
+	upper := maxOverride(res)
+	if upper == 0 {
+		return h.result, fmt.Errorf("%w: no ECS CPU limit to bound GOMAXPROCS", ErrOutOfBounds)
+	}
+
+	if procs < minProcs || procs > upper {
+		return h.result, fmt.Errorf("%w: %d is not from %d to %d", ErrOutOfBounds, procs, minProcs, upper)
+	}
+
+	if !h.overridden {
+		h.computed, h.overridden = h.result, true
+	}
+
+	logEvent(h.cfg, slog.LevelWarn, "maxprocs: Overriding GOMAXPROCS",
+		[]slog.Attr{slog.Int(keyProcs, procs), slog.Int(keyPrevious, h.result.Procs)},
+		"maxprocs: Overriding GOMAXPROCS=%v (was %v)", procs, h.result.Procs)
+	setMaxProcs(procs)
+
+	h.result.Procs = procs
+	h.result.Source = SourceAdmin
+	state.stats.recordOverride(h.result)
+
+	return h.result, nil
+}
+
+// Revert reverts an override, setting GOMAXPROCS back to the value resolved from the ECS
+// metadata. Revert is a no-op if GOMAXPROCS is not overridden.
+func (h *Handle) Revert() (Result, error) {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if h.reset {
+		return h.result, errHandleReset
+	}
+
+	if h.overridden {
+		h.revert()
+	}
+
+	return h.result, nil
+}
+
+// revert reverts the override. The state mutex must be held.
+func (h *Handle) revert() {
+	computed := h.computed
+
+	logEvent(h.cfg, slog.LevelWarn, "maxprocs: Reverting GOMAXPROCS override",
+		[]slog.Attr{slog.Int(keyProcs, computed.Procs), slog.Int(keyPrevious, h.result.Procs)},
+		"maxprocs: Reverting GOMAXPROCS override to %v (was %v)", computed.Procs, h.result.Procs)
+
+	if computed.Source.changed() {
+		setMaxProcs(computed.Procs)
+	} else {
+		resetMaxProcs(h.cfg, computed.Procs)
+	}
+
+	h.result, h.computed, h.overridden = computed, Result{}, false
+	state.stats.recordOverride(h.result)
+}
+
+// maxOverride returns the max GOMAXPROCS may be overridden to, the ECS CPU limit
+// rounded up, or 0 if there is no CPU limit.
+func maxOverride(res Result) int {
+	vcpus := res.TaskCPU
+	if res.ContainerCPU > 0 {
+		vcpus = res.ContainerVCPUs()
+	}
+
+	return int(math.Ceil(vcpus))
+}
@@ -0,0 +1,83 @@
+//go:build go1.25
+
+package maxprocs
+
+import (
+	"log/slog"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+)
+
+const (
+	godebugEnv               = "GODEBUG"
+	godebugDefaultKey        = "DefaultGODEBUG"
+	godebugContainerMaxProcs = "containermaxprocs"
+)
+
+// runtimeContainerAware returns true when the runtime sets GOMAXPROCS based on the
+// cgroup CPU limit and periodically updates it. This is the case from Go 1.25 unless
+// disabled by the containermaxprocs GODEBUG setting, which defaults to disabled when
+// the main module's go.mod declares a Go version prior to 1.25.
+func runtimeContainerAware() bool {
+	return containerMaxProcs()
+}
+
+// containerMaxProcs returns the effective containermaxprocs GODEBUG setting. The GODEBUG
+// environment variable takes precedence over the default of the main module, recorded
+// as DefaultGODEBUG in the build info, which includes any //go:debug directives.
+func containerMaxProcs() bool {
+	value := ""
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, setting := range info.Settings {
+			if setting.Key == godebugDefaultKey {
+				value, _ = godebugValue(setting.Value, godebugContainerMaxProcs)
+			}
+		}
+	}
+
+	if env, ok := godebugValue(os.Getenv(godebugEnv), godebugContainerMaxProcs); ok {
+		value = env
+	}
+
+	return value != "0"
+}
+
+// godebugValue returns the value of key in the comma separated GODEBUG settings,
+// where the last setting of a key takes precedence, and true if the key is set.
+func godebugValue(settings, key string) (string, bool) {
+	var value string
+
+	var found bool
+
+	for _, setting := range strings.Split(settings, ",") {
+		if k, v, ok := strings.Cut(setting, "="); ok && k == key {
+			value, found = v, true
+		}
+	}
+
+	return value, found
+}
+
+// resetMaxProcs restores the runtime default GOMAXPROCS. Calling runtime.GOMAXPROCS
+// disables the runtime's periodic updates, restoring the default re-enables them. The
+// runtime default ignores the GOMAXPROCS environment variable, so when a valid value is
+// set, such as when ignored or clamped by the env policy, prevProcs is restored instead.
+func resetMaxProcs(cfg config.Config, prevProcs int) {
+	if procs, err := strconv.Atoi(os.Getenv(maxProcsKey)); err == nil && procs >= minProcs {
+		logEvent(cfg, slog.LevelInfo, "maxprocs: Resetting GOMAXPROCS", []slog.Attr{slog.Int(keyProcs, prevProcs)},
+			"maxprocs: Resetting GOMAXPROCS to %v", prevProcs)
+		setMaxProcs(prevProcs)
+
+		return
+	}
+
+	logEvent(cfg, slog.LevelInfo, "maxprocs: Resetting GOMAXPROCS to runtime default", nil,
+		"maxprocs: Resetting GOMAXPROCS to runtime default")
+	runtime.SetDefaultGOMAXPROCS()
+}
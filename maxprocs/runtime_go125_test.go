@@ -0,0 +1,166 @@
+//go:build go1.25
+
+package maxprocs_test
+
+import (
+	"bytes"
+	"log"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+	"github.com/rdforte/gomaxecs/internal/task/tasktest"
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+func TestMaxProcs_Set_UndoResetsGOMAXPROCSToRuntimeDefault(t *testing.T) {
+	runtime.SetDefaultGOMAXPROCS()
+	defaultProcs := runtime.GOMAXPROCS(0)
+
+	runtime.GOMAXPROCS(5)
+
+	taskCPU := 10
+	containerCPU := 0
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+
+	undo, _ := maxprocs.Set(maxprocs.WithLogger(logger.Printf))
+
+	assert.Equal(t, taskCPU, runtime.GOMAXPROCS(0)) // GOMAXPROCS should be set to taskCPU
+
+	undo() // reset GOMAXPROCS
+
+	assert.Equal(t, defaultProcs, runtime.GOMAXPROCS(0)) // GOMAXPROCS should be reset to the runtime default
+
+	assert.Contains(t, buf.String(), "maxprocs: Resetting GOMAXPROCS to runtime default")
+}
+
+func TestMaxProcs_Set_UndoRestoresGOMAXPROCSSetInEnvironment(t *testing.T) {
+	for _, policy := range []maxprocs.EnvPolicy{maxprocs.EnvIgnore, maxprocs.EnvClamp} {
+		t.Run(policy.String(), func(t *testing.T) {
+			t.Setenv("GOMAXPROCS", "3")
+			runtime.GOMAXPROCS(3)
+
+			agent := tasktest.NewECSAgent(t).
+				WithContainerMetaEndpoint(2048).
+				WithTaskMetaEndpoint(2048, 2).
+				Start().
+				SetMetaURIEnv()
+			defer agent.Close()
+
+			buf := new(bytes.Buffer)
+			logger := log.New(buf, "", 0)
+
+			undo, err := maxprocs.Set(maxprocs.WithEnvPolicy(policy), maxprocs.WithLogger(logger.Printf))
+			require.NoError(t, err)
+
+			assert.Equal(t, 2, runtime.GOMAXPROCS(0))
+
+			undo()
+
+			assert.Equal(t, 3, runtime.GOMAXPROCS(0))
+			assert.Contains(t, buf.String(), "maxprocs: Resetting GOMAXPROCS to 3")
+		})
+	}
+}
+
+func TestMaxProcs_Set_RuntimePolicy(t *testing.T) {
+	tableTest := []struct {
+		name      string
+		policy    maxprocs.RuntimePolicy
+		godebug   string
+		cgroupFS  fstest.MapFS
+		wantProcs int
+		wantLog   string
+	}{
+		{
+			name:    "should defer to runtime when policy is RuntimeDefer and cgroup CPU quota exists",
+			policy:  maxprocs.RuntimeDefer,
+			godebug: "containermaxprocs=1",
+			cgroupFS: fstest.MapFS{
+				"sys/fs/cgroup/cpu.max": {Data: []byte("300000 100000\n")},
+			},
+			wantProcs: 1,
+			wantLog:   "maxprocs: Deferring to Go runtime GOMAXPROCS=1 as cgroup CPU quota of 3 is set",
+		},
+		{
+			name:   "should set GOMAXPROCS when policy is RuntimeDefer and containermaxprocs defaults to disabled",
+			policy: maxprocs.RuntimeDefer,
+			cgroupFS: fstest.MapFS{
+				"sys/fs/cgroup/cpu.max": {Data: []byte("300000 100000\n")},
+			},
+			wantProcs: 2,
+			wantLog:   "maxprocs: Not deferring to Go runtime as it is not container aware",
+		},
+		{
+			name:    "should set GOMAXPROCS when policy is RuntimeDefer and containermaxprocs is disabled",
+			policy:  maxprocs.RuntimeDefer,
+			godebug: "containermaxprocs=1,containermaxprocs=0",
+			cgroupFS: fstest.MapFS{
+				"sys/fs/cgroup/cpu.max": {Data: []byte("300000 100000\n")},
+			},
+			wantProcs: 2,
+			wantLog:   "maxprocs: Updated GOMAXPROCS=2",
+		},
+		{
+			name:    "should set GOMAXPROCS when policy is RuntimeDefer and no cgroup CPU quota exists",
+			policy:  maxprocs.RuntimeDefer,
+			godebug: "containermaxprocs=1",
+			cgroupFS: fstest.MapFS{
+				"sys/fs/cgroup/cpu.max": {Data: []byte("max 100000\n")},
+			},
+			wantProcs: 2,
+			wantLog:   "maxprocs: Updated GOMAXPROCS=2",
+		},
+		{
+			name:   "should set GOMAXPROCS when policy is RuntimeOverride and cgroup CPU quota exists",
+			policy: maxprocs.RuntimeOverride,
+			cgroupFS: fstest.MapFS{
+				"sys/fs/cgroup/cpu.max": {Data: []byte("300000 100000\n")},
+			},
+			wantProcs: 2,
+			wantLog:   "maxprocs: Updated GOMAXPROCS=2",
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GODEBUG", tt.godebug)
+			runtime.GOMAXPROCS(1)
+
+			agent := tasktest.NewECSAgent(t).
+				WithContainerMetaEndpoint(containerCPU).
+				WithTaskMetaEndpoint(containerCPU, taskCPU).
+				Start().
+				SetMetaURIEnv()
+			defer agent.Close()
+
+			buf := new(bytes.Buffer)
+			logger := log.New(buf, "", 0)
+
+			withCgroupFS := func(cfg *config.Config) { cfg.CgroupFS = tt.cgroupFS }
+
+			undo, _ := maxprocs.Set(
+				maxprocs.WithLogger(logger.Printf),
+				maxprocs.WithRuntimePolicy(tt.policy),
+				withCgroupFS,
+			)
+			defer undo()
+
+			assert.Equal(t, tt.wantProcs, runtime.GOMAXPROCS(0))
+			assert.Contains(t, buf.String(), tt.wantLog)
+		})
+	}
+}
@@ -0,0 +1,22 @@
+//go:build !go1.25
+
+package maxprocs
+
+import (
+	"log/slog"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+)
+
+// runtimeContainerAware returns false as prior to Go 1.25 the runtime does not
+// take the cgroup CPU limit into account.
+func runtimeContainerAware() bool {
+	return false
+}
+
+// resetMaxProcs restores GOMAXPROCS to its previous value.
+func resetMaxProcs(cfg config.Config, prevProcs int) {
+	logEvent(cfg, slog.LevelInfo, "maxprocs: Resetting GOMAXPROCS", []slog.Attr{slog.Int(keyProcs, prevProcs)},
+		"maxprocs: Resetting GOMAXPROCS to %v", prevProcs)
+	setMaxProcs(prevProcs)
+}
@@ -0,0 +1,44 @@
+//go:build !go1.25
+
+package maxprocs_test
+
+import (
+	"bytes"
+	"fmt"
+	"log"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rdforte/gomaxecs/internal/task/tasktest"
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+func TestMaxProcs_Set_UndoResetsGOMAXPROCS(t *testing.T) {
+	initialProcs := 5
+	runtime.GOMAXPROCS(initialProcs)
+
+	taskCPU := 10
+	containerCPU := 0
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(containerCPU).
+		WithTaskMetaEndpoint(containerCPU, taskCPU).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	buf := new(bytes.Buffer)
+	logger := log.New(buf, "", 0)
+
+	undo, _ := maxprocs.Set(maxprocs.WithLogger(logger.Printf))
+
+	assert.Equal(t, taskCPU, runtime.GOMAXPROCS(0)) // GOMAXPROCS should be set to taskCPU
+
+	undo() // reset GOMAXPROCS
+
+	assert.Equal(t, initialProcs, runtime.GOMAXPROCS(0)) // GOMAXPROCS should be reset to initialProcs
+
+	assert.Contains(t, buf.String(), fmt.Sprintf("maxprocs: Resetting GOMAXPROCS to %v", initialProcs))
+}
@@ -0,0 +1,45 @@
+package maxprocs
+
+// Stats describes setting GOMAXPROCS over the lifetime of the process.
+type Stats struct {
+	// Last is the result of the last attempt to set GOMAXPROCS, whether or not it succeeded,
+	// or of the last override or revert, see Handle.Override.
+	Last Result
+	// Attempts is the number of times GOMAXPROCS was resolved, including refreshes.
+	Attempts uint64
+	// Errors is the number of times GOMAXPROCS could not be resolved.
+	Errors uint64
+	// LastError is the error of the last failed attempt, or nil if none failed.
+	LastError error
+	// Retries is the number of metadata requests retried.
+	Retries uint64
+	// Drifts is the number of times the drift watchdog found GOMAXPROCS changed by
+	// other code, see WithWatchdog.
+	Drifts uint64
+}
+
+// ReadStats returns the stats of setting GOMAXPROCS in the process.
+func ReadStats() Stats {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	return state.stats
+}
+
+// record records an attempt to set GOMAXPROCS. The state mutex must be held.
+func (s *Stats) record(res Result, retries uint64, err error) {
+	s.Last = res
+	s.Attempts++
+	s.Retries += retries
+
+	if err != nil {
+		s.Errors++
+		s.LastError = err
+	}
+}
+
+// recordOverride records GOMAXPROCS overridden or reverted as the last result, without
+// counting an attempt. The state mutex must be held.
+func (s *Stats) recordOverride(res Result) {
+	s.Last = res
+}
@@ -0,0 +1,128 @@
+package maxprocs
+
+import (
+	"errors"
+	"log/slog"
+	"math"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strings"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+	ecstask "github.com/rdforte/gomaxecs/internal/task"
+)
+
+const (
+	memLimitKey     = "GOMEMLIMIT"
+	bytesPerMiB     = 1 << 20
+	fromLabel       = "container label"
+	fromTaskTag     = "task tag"
+	fromInstanceTag = "container instance tag"
+)
+
+// tuning returns the tuning for the container. The tuning set by the options is
+// overridden by the container instance tags, then the task tags and finally the
+// container labels.
+func (r *resolution) tuning(limits ecstask.Limits) config.Tuning {
+	if errors.Is(limits.TagsErr, ecstask.ErrTagsIncomplete) {
+		logEvent(r.cfg, slog.LevelWarn, "maxprocs: Using incomplete task tags",
+			[]slog.Attr{slog.Any(keyError, limits.TagsErr)},
+			"maxprocs: Using incomplete task tags: %v", limits.TagsErr)
+	} else if limits.TagsErr != nil {
+		logEvent(r.cfg, slog.LevelWarn, "maxprocs: Falling back to task metadata without tags",
+			[]slog.Attr{slog.Any(keyError, limits.TagsErr)},
+			"maxprocs: Falling back to task metadata without tags: %v", limits.TagsErr)
+	}
+
+	tuning := r.cfg.Tuning
+	overrideTuning(r.cfg, &tuning, fromInstanceTag, limits.ContainerInstanceTags)
+	overrideTuning(r.cfg, &tuning, fromTaskTag, limits.TaskTags)
+	overrideTuning(r.cfg, &tuning, fromLabel, limits.Labels)
+
+	return tuning
+}
+
+// overrideTuning overrides the tuning with the values whose keys have the tuning prefix,
+// in key order. Unknown keys and invalid values are reported and ignored.
+func overrideTuning(cfg config.Config, tuning *config.Tuning, from string, values map[string]string) {
+	keys := make([]string, 0, len(values))
+
+	for key := range values {
+		if strings.HasPrefix(key, config.TuningPrefix) {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	for _, key := range keys {
+		value := values[key]
+
+		if err := tuning.Set(strings.TrimPrefix(key, config.TuningPrefix), value); err != nil {
+			logEvent(cfg, slog.LevelWarn, "maxprocs: Ignoring invalid tuning override",
+				[]slog.Attr{slog.String(keyFrom, from), slog.String(keyKey, key), slog.Any(keyError, err)},
+				"maxprocs: Ignoring %s %s: %v", from, key, err)
+
+			continue
+		}
+
+		logEvent(cfg, slog.LevelInfo, "maxprocs: Overriding tuning",
+			[]slog.Attr{slog.String(keyFrom, from), slog.String(keyKey, key), slog.String(keyValue, value)},
+			"maxprocs: Overriding tuning with %s %s=%q", from, key, value)
+	}
+}
+
+// tunedMaxProcs returns the value GOMAXPROCS should be set to and its source. A fixed
+// value takes precedence, otherwise the headroom is held back from the ECS limits.
+func tunedMaxProcs(limits ecstask.Limits, tuning config.Tuning) (int, Source) {
+	if tuning.Procs > 0 {
+		return tuning.Procs, SourceOverride
+	}
+
+	procs := limits.MaxProcs()
+	if tuning.Headroom > 0 {
+		procs = max(int(math.Floor(float64(procs)*(1-tuning.Headroom))), minProcs)
+	}
+
+	return procs, SourceECS
+}
+
+// memoryLimit returns the Go memory limit in bytes, as the memory limit ratio of the
+// container memory limit, falling back to the task memory limit. A value of 0 is
+// returned when the memory limit should be left unchanged.
+func memoryLimit(cfg config.Config, limits ecstask.Limits, tuning config.Tuning) int64 {
+	if tuning.MemoryLimitRatio == 0 {
+		return 0
+	}
+
+	if env, ok := os.LookupEnv(memLimitKey); ok {
+		logEvent(cfg, slog.LevelInfo, "maxprocs: Honoring GOMEMLIMIT as set in environment",
+			[]slog.Attr{slog.String(keyGOMEMLIMIT, env)},
+			"maxprocs: Honoring GOMEMLIMIT=%q as set in environment", env)
+
+		return 0
+	}
+
+	mem := limits.ContainerMemory
+	if mem == 0 {
+		mem = limits.TaskMemory
+	}
+
+	if mem == 0 {
+		logEvent(cfg, slog.LevelWarn, "maxprocs: No memory limit found for task or container, leaving memory limit unchanged",
+			nil, "maxprocs: No memory limit found for task or container, leaving memory limit unchanged")
+
+		return 0
+	}
+
+	return int64(mem * tuning.MemoryLimitRatio * bytesPerMiB)
+}
+
+func prevMemoryLimit() int64 {
+	return debug.SetMemoryLimit(-1)
+}
+
+func setMemoryLimit(limit int64) {
+	debug.SetMemoryLimit(limit)
+}
@@ -0,0 +1,75 @@
+package maxprocs
+
+import (
+	"log/slog"
+	"runtime"
+	"time"
+)
+
+// watch starts the drift watchdog when enabled, until the Handle is reset. The state
+// mutex must be held.
+func (h *Handle) watch() {
+	interval := h.cfg.Watchdog.Interval
+	if interval <= 0 || h.cfg.DryRun {
+		return
+	}
+
+	h.stop = make(chan struct{})
+	go h.runWatchdog(h.stop, interval)
+}
+
+func (h *Handle) runWatchdog(stop <-chan struct{}, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			h.checkDrift()
+		}
+	}
+}
+
+// checkDrift compares GOMAXPROCS with the value applied by the Handle, counting and
+// logging drift, and re-applying the value if configured. Drift is only counted and
+// logged when the drifted value changes, so a persistent drift is reported once.
+// GOMAXPROCS left to the environment or the runtime is not checked, as it was not
+// applied by the Handle.
+func (h *Handle) checkDrift() {
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	if h.reset || !h.result.Source.changed() {
+		return
+	}
+
+	current := runtime.GOMAXPROCS(0)
+	if current == h.result.Procs {
+		h.drifted = 0
+		return
+	}
+
+	if current == h.drifted {
+		return
+	}
+
+	h.drifted = current
+	state.stats.Drifts++
+
+	logEvent(h.cfg, slog.LevelWarn, "maxprocs: GOMAXPROCS changed by other code",
+		[]slog.Attr{slog.Int(keyProcs, h.result.Procs), slog.Int(keyCurrent, current)},
+		"maxprocs: GOMAXPROCS changed by other code to %v (set to %v)", current, h.result.Procs)
+
+	if !h.cfg.Watchdog.Reapply {
+		return
+	}
+
+	logEvent(h.cfg, slog.LevelInfo, "maxprocs: Reapplying GOMAXPROCS",
+		[]slog.Attr{slog.Int(keyProcs, h.result.Procs)},
+		"maxprocs: Reapplying GOMAXPROCS=%v", h.result.Procs)
+	setMaxProcs(h.result.Procs)
+
+	h.drifted = 0
+}
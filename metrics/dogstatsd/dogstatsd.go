@@ -0,0 +1,245 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package dogstatsd sends how gomaxecs set GOMAXPROCS as gauges in the DogStatsD
+// format over UDP, such as to a Datadog agent sidecar, tagged with the ECS task identity.
+//
+//	emitter, err := dogstatsd.New()
+//	if err != nil {
+//		// handle error
+//	}
+//	defer emitter.Close()
+//
+//	go emitter.Run(ctx, 10*time.Second)
+package dogstatsd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+	ecstask "github.com/rdforte/gomaxecs/internal/task"
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+const (
+	// DefaultAddr is the default address of the DogStatsD agent.
+	DefaultAddr = "localhost:8125"
+	// DefaultPrefix is the default prefix of the gauge names.
+	DefaultPrefix = "gomaxecs."
+)
+
+// Option represents a configuration option for the Emitter.
+type Option func(*Emitter)
+
+// WithAddr sets the UDP address of the DogStatsD agent. Defaults to DefaultAddr.
+func WithAddr(addr string) Option {
+	return func(e *Emitter) {
+		e.addr = addr
+	}
+}
+
+// WithPrefix sets the prefix of the gauge names. Defaults to DefaultPrefix.
+func WithPrefix(prefix string) Option {
+	return func(e *Emitter) {
+		e.prefix = prefix
+	}
+}
+
+// WithTags adds tags, in the key:value form, to the gauges alongside the task identity tags.
+func WithTags(tags ...string) Option {
+	return func(e *Emitter) {
+		e.tags = append(e.tags, tags...)
+	}
+}
+
+// Emitter sends the gauges to a DogStatsD agent.
+type Emitter struct {
+	addr   string
+	prefix string
+	tags   []string
+	conn   net.Conn
+	task   *ecstask.Task
+
+	mu        sync.Mutex
+	lastStats ecstask.Stats
+}
+
+// New returns an Emitter sending to the DogStatsD agent.
+func New(opts ...Option) (*Emitter, error) {
+	e := &Emitter{
+		addr:   DefaultAddr,
+		prefix: DefaultPrefix,
+		task:   ecstask.New(config.New()),
+	}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	conn, err := net.Dial("udp", e.addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial DogStatsD agent: %w", err)
+	}
+
+	e.conn = conn
+
+	return e, nil
+}
+
+// Run emits the gauges every interval until ctx is done. As the gauges are sent over
+// UDP, failing to emit is not reported and is retried on the next interval.
+func (e *Emitter) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		_ = e.Emit(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Emit sends the gauges in a single datagram. ctx is used to read the ECS metadata.
+//
+// The gauges are the current GOMAXPROCS and Go memory limit, the ECS container and task
+// CPU and memory limits, and the ratio of CFS periods the container was throttled in since
+// the previous emit, or since the container started on the first emit. The throttled ratio
+// is omitted if the ECS container stats can not be read.
+func (e *Emitter) Emit(ctx context.Context) error {
+	res := maxprocs.ReadStats().Last
+
+	id := res.Identity
+	if id == (maxprocs.Identity{}) && maxprocs.IsECS() {
+		id, _ = maxprocs.TaskMetadata(ctx)
+	}
+
+	g := &gauges{prefix: e.prefix, tags: tags(id, e.tags)}
+
+	g.write("gomaxprocs", float64(runtime.GOMAXPROCS(0)))
+	g.write("container.cpu_limit_vcpus", res.ContainerVCPUs())
+	g.write("task.cpu_limit_vcpus", res.TaskCPU)
+	g.write("container.memory_limit_bytes", float64(res.ContainerMemoryBytes()))
+	g.write("task.memory_limit_bytes", float64(res.TaskMemoryBytes()))
+	g.write("go_memory_limit_bytes", float64(debug.SetMemoryLimit(-1)))
+
+	if ratio, ok := e.throttledRatio(ctx); ok {
+		g.write("cpu.throttled_ratio", ratio)
+	}
+
+	if _, err := e.conn.Write(bytes.TrimSuffix(g.buf.Bytes(), []byte("\n"))); err != nil {
+		return fmt.Errorf("failed to send gauges: %w", err)
+	}
+
+	return nil
+}
+
+// Close closes the connection to the DogStatsD agent.
+func (e *Emitter) Close() error {
+	return e.conn.Close() //nolint:wrapcheck // return underlying error.
+}
+
+// throttledRatio returns the ratio of CFS periods the container was throttled in since
+// the stats were last read.
+func (e *Emitter) throttledRatio(ctx context.Context) (float64, bool) {
+	stats, err := e.task.GetStats(ctx)
+	if err != nil {
+		return 0, false
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	last := e.lastStats
+	e.lastStats = stats
+
+	// the counters restart from zero if the container is restarted.
+	if stats.Periods < last.Periods || stats.ThrottledPeriods < last.ThrottledPeriods {
+		last = ecstask.Stats{}
+	}
+
+	periods := stats.Periods - last.Periods
+	if periods == 0 {
+		return 0, true
+	}
+
+	return float64(stats.ThrottledPeriods-last.ThrottledPeriods) / float64(periods), true
+}
+
+// gauges buffers gauges sharing the same tags, one per line.
+type gauges struct {
+	buf    bytes.Buffer
+	prefix string
+	tags   string
+}
+
+func (g *gauges) write(name string, value float64) {
+	fmt.Fprintf(&g.buf, "%s%s:%s|g%s\n", g.prefix, name, strconv.FormatFloat(value, 'f', -1, 64), g.tags)
+}
+
+// tags returns the tags section of the gauges, including the task identity tags using
+// the names of the tags set by the Datadog agent for ECS.
+func tags(id maxprocs.Identity, extra []string) string {
+	identity := [][2]string{
+		{"ecs_cluster_name", id.ClusterName()},
+		{"ecs_service", id.ServiceName},
+		{"task_family", id.Family},
+		{"task_version", id.Revision},
+		{"container_name", id.ContainerName},
+	}
+
+	all := make([]string, 0, len(identity)+len(extra))
+
+	for _, tag := range identity {
+		if tag[1] != "" {
+			all = append(all, tag[0]+":"+sanitize(tag[1]))
+		}
+	}
+
+	for _, tag := range extra {
+		all = append(all, sanitize(tag))
+	}
+
+	if len(all) == 0 {
+		return ""
+	}
+
+	return "|#" + strings.Join(all, ",")
+}
+
+//nolint:gochecknoglobals // replacer is safe for concurrent use.
+var tagReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
+
+// sanitize replaces the characters which delimit the DogStatsD format.
+func sanitize(tag string) string {
+	return tagReplacer.Replace(tag)
+}
@@ -0,0 +1,144 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package dogstatsd_test
+
+import (
+	"context"
+	"net"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rdforte/gomaxecs/internal/task/tasktest"
+	"github.com/rdforte/gomaxecs/maxprocs"
+	"github.com/rdforte/gomaxecs/metrics/dogstatsd"
+)
+
+func TestDogStatsD_Emitter_EmitsGauges(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(`{"DockerId":"container-id","Name":"app","Limits":{"CPU":2048,"Memory":512}}`).
+		WithTaskMetaEndpointJSON(`{"Cluster":"arn:aws:ecs:us-east-1:123456789012:cluster/default",`+
+			`"ServiceName":"api","Family":"service","Revision":"7",`+
+			`"Containers":[{"DockerId":"container-id","Limits":{"CPU":2048}}],"Limits":{"CPU":4,"Memory":1024}}`).
+		WithContainerStatsEndpoint(100, 25, int(2*time.Second)).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	undo, err := maxprocs.Set()
+	require.NoError(t, err)
+	defer undo()
+
+	listener := listen(t)
+
+	emitter, err := dogstatsd.New(dogstatsd.WithAddr(listener.LocalAddr().String()), dogstatsd.WithTags("env:prod"))
+	require.NoError(t, err)
+	defer emitter.Close()
+
+	tags := "|#ecs_cluster_name:default,ecs_service:api,task_family:service,task_version:7,container_name:app,env:prod"
+	memLimit := strconv.FormatFloat(float64(debug.SetMemoryLimit(-1)), 'f', -1, 64)
+
+	require.NoError(t, emitter.Emit(context.Background()))
+	assert.Equal(t, []string{
+		"gomaxecs.gomaxprocs:2|g" + tags,
+		"gomaxecs.container.cpu_limit_vcpus:2|g" + tags,
+		"gomaxecs.task.cpu_limit_vcpus:4|g" + tags,
+		"gomaxecs.container.memory_limit_bytes:536870912|g" + tags,
+		"gomaxecs.task.memory_limit_bytes:1073741824|g" + tags,
+		"gomaxecs.go_memory_limit_bytes:" + memLimit + "|g" + tags,
+		"gomaxecs.cpu.throttled_ratio:0.25|g" + tags,
+	}, read(t, listener))
+
+	require.NoError(t, emitter.Emit(context.Background()))
+	assert.Contains(t, read(t, listener), "gomaxecs.cpu.throttled_ratio:0|g"+tags)
+}
+
+func TestDogStatsD_Emitter_OmitsThrottledRatioWhenStatsUnavailable(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "")
+
+	listener := listen(t)
+
+	emitter, err := dogstatsd.New(dogstatsd.WithAddr(listener.LocalAddr().String()), dogstatsd.WithPrefix("app."))
+	require.NoError(t, err)
+	defer emitter.Close()
+
+	require.NoError(t, emitter.Emit(context.Background()))
+
+	gauges := read(t, listener)
+	assert.Len(t, gauges, 6)
+	assert.True(t, strings.HasPrefix(gauges[0], "app.gomaxprocs:"))
+
+	for _, gauge := range gauges {
+		assert.NotContains(t, gauge, "throttled_ratio")
+	}
+}
+
+func TestDogStatsD_Emitter_Run(t *testing.T) {
+	listener := listen(t)
+
+	emitter, err := dogstatsd.New(dogstatsd.WithAddr(listener.LocalAddr().String()))
+	require.NoError(t, err)
+	defer emitter.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		emitter.Run(ctx, time.Millisecond)
+	}()
+
+	read(t, listener)
+	read(t, listener)
+	cancel()
+	<-done
+}
+
+func listen(t *testing.T) net.PacketConn {
+	t.Helper()
+
+	listener, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+
+	return listener
+}
+
+// read reads a datagram from the listener, returning its gauges.
+func read(t *testing.T, listener net.PacketConn) []string {
+	t.Helper()
+
+	require.NoError(t, listener.SetReadDeadline(time.Now().Add(time.Second)))
+
+	buf := make([]byte, 8192)
+	n, _, err := listener.ReadFrom(buf)
+	require.NoError(t, err)
+
+	return strings.Split(string(buf[:n]), "\n")
+}
@@ -0,0 +1,73 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package expvar publishes how gomaxecs set GOMAXPROCS as the "gomaxecs" expvar map.
+//
+// Importing the package publishes the map, which is served alongside the other
+// expvars at /debug/vars when the default HTTP mux is served.
+//
+//	import _ "github.com/rdforte/gomaxecs/metrics/expvar"
+//
+// The map is read from a single snapshot of the stats on each request, so it always
+// reflects the latest attempt to set GOMAXPROCS, including refreshes.
+package expvar
+
+import (
+	"expvar"
+
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+// Name is the name of the published expvar map.
+const Name = "gomaxecs"
+
+func init() {
+	expvar.Publish(Name, expvar.Func(readMap))
+}
+
+// readMap returns the map of vars from a single read of the latest maxprocs stats,
+// so the vars are consistent with each other.
+func readMap() any {
+	s := maxprocs.ReadStats()
+
+	return map[string]any{
+		"procs":            s.Last.Procs,
+		"previous":         s.Last.Previous,
+		"source":           s.Last.Source,
+		"container_cpu":    s.Last.ContainerCPU,
+		"task_cpu":         s.Last.TaskCPU,
+		"memory_limit":     s.Last.MemoryLimit,
+		"duration_seconds": s.Last.Duration.Seconds(),
+		"dry_run":          s.Last.DryRun,
+		"attempts":         s.Attempts,
+		"errors":           s.Errors,
+		"retries":          s.Retries,
+		"drifts":           s.Drifts,
+		"last_error":       lastError(s),
+	}
+}
+
+func lastError(s maxprocs.Stats) string {
+	if s.LastError == nil {
+		return ""
+	}
+
+	return s.LastError.Error()
+}
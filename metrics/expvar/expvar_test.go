@@ -0,0 +1,81 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package expvar_test
+
+import (
+	"encoding/json"
+	"expvar"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rdforte/gomaxecs/internal/task/tasktest"
+	"github.com/rdforte/gomaxecs/maxprocs"
+	ecsexpvar "github.com/rdforte/gomaxecs/metrics/expvar"
+)
+
+func TestExpvar_PublishesLatestStats(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+
+	failing := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(1<<10).
+		WithTaskMetaEndpointUnavailable(2, 1<<10, 4).
+		Start().
+		SetMetaURIEnv()
+
+	_, err := maxprocs.Set(maxprocs.WithRetry(1, time.Millisecond))
+	require.Error(t, err)
+	failing.Close()
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpoint(2<<10).
+		WithTaskMetaEndpoint(2<<10, 4).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	undo, err := maxprocs.Set()
+	require.NoError(t, err)
+	defer undo()
+
+	v := expvar.Get(ecsexpvar.Name)
+	require.NotNil(t, v)
+
+	var got map[string]any
+	require.NoError(t, json.Unmarshal([]byte(v.String()), &got))
+
+	assert.InDelta(t, 2, got["procs"], 0)
+	assert.InDelta(t, 1, got["previous"], 0)
+	assert.Equal(t, "ecs", got["source"])
+	assert.InDelta(t, 2<<10, got["container_cpu"], 0)
+	assert.InDelta(t, 4, got["task_cpu"], 0)
+	assert.InDelta(t, 0, got["memory_limit"], 0)
+	assert.Positive(t, got["duration_seconds"])
+	assert.Equal(t, false, got["dry_run"])
+	assert.InDelta(t, 2, got["attempts"], 0)
+	assert.InDelta(t, 1, got["errors"], 0)
+	assert.InDelta(t, 1, got["retries"], 0)
+	assert.InDelta(t, 0, got["drifts"], 0)
+	assert.Contains(t, got["last_error"], "request failed, status code: 503")
+}
@@ -0,0 +1,203 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package prometheus exposes how gomaxecs set GOMAXPROCS as metrics in the
+// Prometheus text exposition format, without depending on the Prometheus client.
+//
+//	http.Handle("/metrics", prometheus.Handler())
+//
+// All metrics are labelled with the ECS task family and container name, falling back to
+// the cached task metadata when GOMAXPROCS was not resolved from it.
+package prometheus
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+	ecstask "github.com/rdforte/gomaxecs/internal/task"
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+const (
+	contentType = "text/plain; version=0.0.4; charset=utf-8"
+	typeGauge   = "gauge"
+	typeCounter = "counter"
+)
+
+// Option represents a configuration option for the metrics.
+type Option func(*exporter)
+
+// WithThrottling includes the CPU throttling counters of the container, read from the
+// ECS container stats on each scrape. By default, throttling counters are not included.
+func WithThrottling() Option {
+	return func(e *exporter) {
+		e.throttling = true
+	}
+}
+
+// Handler returns an http.Handler serving the metrics in the Prometheus text exposition format.
+func Handler(opts ...Option) http.Handler {
+	return newExporter(opts...)
+}
+
+// WriteMetrics writes the metrics in the Prometheus text exposition format to w.
+// ctx is used to read the ECS metadata, such as the container stats when throttling
+// counters are included.
+func WriteMetrics(ctx context.Context, w io.Writer, opts ...Option) error {
+	return newExporter(opts...).write(ctx, w)
+}
+
+type exporter struct {
+	throttling bool
+}
+
+func newExporter(opts ...Option) *exporter {
+	e := &exporter{}
+
+	for _, opt := range opts {
+		opt(e)
+	}
+
+	return e
+}
+
+// containerStats reads the ECS container stats for the throttling counters. It is shared
+// by every Handler and WriteMetrics call, so the failures counter persists across scrapes.
+//
+//nolint:gochecknoglobals // the counters are process wide state.
+var containerStats = &statsReader{}
+
+type statsReader struct {
+	once     sync.Once
+	task     *ecstask.Task
+	failures atomic.Uint64
+}
+
+// read reads the container stats, counting the failures.
+func (s *statsReader) read(ctx context.Context) (ecstask.Stats, error) {
+	s.once.Do(func() {
+		s.task = ecstask.New(config.New())
+	})
+
+	stats, err := s.task.GetStats(ctx)
+	if err != nil {
+		s.failures.Add(1)
+	}
+
+	return stats, err //nolint:wrapcheck // error is already descriptive.
+}
+
+func (e *exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", contentType)
+	_ = e.write(r.Context(), w)
+}
+
+// write writes the metrics to w, returning an error only if writing fails.
+// Failing to read the container stats is counted rather than returned.
+func (e *exporter) write(ctx context.Context, w io.Writer) error {
+	stats := maxprocs.ReadStats()
+	res := stats.Last
+
+	id := res.Identity
+	if id == (maxprocs.Identity{}) && maxprocs.IsECS() {
+		id, _ = maxprocs.TaskMetadata(ctx)
+	}
+
+	m := &metrics{labels: labels(id)}
+
+	m.write("gomaxecs_gomaxprocs", typeGauge, "Current value of GOMAXPROCS.",
+		float64(runtime.GOMAXPROCS(0)))
+	m.write("gomaxecs_container_cpu_limit_vcpus", typeGauge, "ECS container CPU limit in vCPUs.",
+		res.ContainerVCPUs())
+	m.write("gomaxecs_task_cpu_limit_vcpus", typeGauge, "ECS task CPU limit in vCPUs.",
+		res.TaskCPU)
+	m.write("gomaxecs_container_memory_limit_bytes", typeGauge, "ECS container memory limit in bytes.",
+		float64(res.ContainerMemoryBytes()))
+	m.write("gomaxecs_task_memory_limit_bytes", typeGauge, "ECS task memory limit in bytes.",
+		float64(res.TaskMemoryBytes()))
+	m.write("gomaxecs_go_memory_limit_bytes", typeGauge, "Current Go memory limit in bytes.",
+		float64(debug.SetMemoryLimit(-1)))
+	m.write("gomaxecs_resolutions_total", typeCounter, "Number of times GOMAXPROCS was resolved.",
+		float64(stats.Attempts))
+	m.write("gomaxecs_resolution_failures_total", typeCounter, "Number of times GOMAXPROCS could not be resolved.",
+		float64(stats.Errors))
+	m.write("gomaxecs_metadata_retries_total", typeCounter, "Number of ECS metadata requests retried.",
+		float64(stats.Retries))
+	m.write("gomaxecs_drifts_total", typeCounter, "Number of times GOMAXPROCS was found changed by other code.",
+		float64(stats.Drifts))
+
+	if e.throttling {
+		writeThrottling(ctx, m)
+	}
+
+	if _, err := w.Write(m.buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to write metrics: %w", err)
+	}
+
+	return nil
+}
+
+func writeThrottling(ctx context.Context, m *metrics) {
+	stats, err := containerStats.read(ctx)
+	if err == nil {
+		m.write("gomaxecs_cpu_periods_total", typeCounter, "Number of CFS enforcement periods elapsed.",
+			float64(stats.Periods))
+		m.write("gomaxecs_cpu_throttled_periods_total", typeCounter, "Number of periods the container was throttled.",
+			float64(stats.ThrottledPeriods))
+		m.write("gomaxecs_cpu_throttled_seconds_total", typeCounter, "Total time the container was throttled.",
+			stats.ThrottledTime.Seconds())
+	}
+
+	m.write("gomaxecs_stats_failures_total", typeCounter, "Number of times the ECS container stats could not be read.",
+		float64(containerStats.failures.Load()))
+}
+
+// metrics buffers metrics sharing the same labels.
+type metrics struct {
+	buf    bytes.Buffer
+	labels string
+}
+
+func (m *metrics) write(name, typ, help string, value float64) {
+	fmt.Fprintf(&m.buf, "# HELP %s %s\n# TYPE %s %s\n%s{%s} %s\n",
+		name, help, name, typ, name, m.labels, strconv.FormatFloat(value, 'g', -1, 64))
+}
+
+func labels(id maxprocs.Identity) string {
+	return fmt.Sprintf(`task_family="%s",container_name="%s"`, escape(id.Family), escape(id.ContainerName))
+}
+
+//nolint:gochecknoglobals // replacer is safe for concurrent use.
+var labelReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escape escapes a label value as required by the text exposition format.
+func escape(value string) string {
+	return labelReplacer.Replace(value)
+}
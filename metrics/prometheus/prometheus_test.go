@@ -0,0 +1,131 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package prometheus_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rdforte/gomaxecs/internal/task/tasktest"
+	"github.com/rdforte/gomaxecs/maxprocs"
+	"github.com/rdforte/gomaxecs/metrics/prometheus"
+)
+
+const (
+	containerJSON = `{"DockerId":"container-id","Name":"app","Limits":{"CPU":2048,"Memory":512}}`
+	taskJSON      = `{"Family":"svc\"1","Containers":[{"DockerId":"container-id","Limits":{"CPU":2048}}],` +
+		`"Limits":{"CPU":4,"Memory":1024}}`
+	labels = `{task_family="svc\"1",container_name="app"}`
+)
+
+func TestPrometheus_Handler_ServesMetrics(t *testing.T) {
+	runtime.GOMAXPROCS(1)
+	prometheus.ResetStats(t)
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(containerJSON).
+		WithTaskMetaEndpointJSON(taskJSON).
+		WithContainerStatsEndpoint(100, 25, int(2*time.Second)).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	undo, err := maxprocs.Set()
+	require.NoError(t, err)
+	defer undo()
+
+	rec := httptest.NewRecorder()
+	prometheus.Handler(prometheus.WithThrottling()).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+	assert.Equal(t, "text/plain; version=0.0.4; charset=utf-8", rec.Header().Get("Content-Type"))
+
+	body := rec.Body.String()
+
+	for _, want := range []string{
+		"# HELP gomaxecs_gomaxprocs Current value of GOMAXPROCS.\n# TYPE gomaxecs_gomaxprocs gauge\n" +
+			"gomaxecs_gomaxprocs" + labels + " 2\n",
+		"gomaxecs_container_cpu_limit_vcpus" + labels + " 2\n",
+		"gomaxecs_task_cpu_limit_vcpus" + labels + " 4\n",
+		"gomaxecs_container_memory_limit_bytes" + labels + " 5.36870912e+08\n",
+		"gomaxecs_task_memory_limit_bytes" + labels + " 1.073741824e+09\n",
+		"# TYPE gomaxecs_resolutions_total counter\n",
+		"# TYPE gomaxecs_resolution_failures_total counter\n",
+		"# TYPE gomaxecs_metadata_retries_total counter\n",
+		"# TYPE gomaxecs_drifts_total counter\n",
+		"gomaxecs_cpu_periods_total" + labels + " 100\n",
+		"gomaxecs_cpu_throttled_periods_total" + labels + " 25\n",
+		"gomaxecs_cpu_throttled_seconds_total" + labels + " 2\n",
+		"gomaxecs_stats_failures_total" + labels + " 0\n",
+	} {
+		assert.Contains(t, body, want)
+	}
+}
+
+func TestPrometheus_WriteMetrics_CountsStatsFailures(t *testing.T) {
+	t.Setenv("ECS_CONTAINER_METADATA_URI_V4", "invalid-uri")
+	prometheus.ResetStats(t)
+
+	for _, want := range []string{"1", "2"} {
+		buf := new(bytes.Buffer)
+		require.NoError(t, prometheus.WriteMetrics(context.Background(), buf, prometheus.WithThrottling()))
+
+		assert.Regexp(t, `gomaxecs_stats_failures_total\{.*\} `+want+`\n`, buf.String())
+		assert.NotContains(t, buf.String(), "gomaxecs_cpu_throttled_periods_total")
+	}
+}
+
+func TestPrometheus_WriteMetrics_LabelsFromTaskMetadata(t *testing.T) {
+	t.Setenv("GOMAXPROCS", "3")
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(containerJSON).
+		WithTaskMetaEndpointJSON(taskJSON).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	undo, err := maxprocs.Set()
+	require.NoError(t, err)
+	defer undo()
+
+	require.Equal(t, maxprocs.SourceEnv, maxprocs.ReadStats().Last.Source)
+
+	buf := new(bytes.Buffer)
+	require.NoError(t, prometheus.WriteMetrics(context.Background(), buf))
+
+	assert.Contains(t, buf.String(), "gomaxecs_gomaxprocs"+labels+" ")
+}
+
+func TestPrometheus_WriteMetrics_ExcludesThrottlingByDefault(t *testing.T) {
+	buf := new(bytes.Buffer)
+	require.NoError(t, prometheus.WriteMetrics(context.Background(), buf))
+
+	assert.Contains(t, buf.String(), "gomaxecs_gomaxprocs")
+	assert.NotContains(t, buf.String(), "gomaxecs_stats_failures_total")
+}
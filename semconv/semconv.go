@@ -0,0 +1,128 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package semconv provides OpenTelemetry semantic convention resource attributes
+// describing the ECS task and container, from the same metadata used to set GOMAXPROCS.
+// The attributes are plain key value pairs, so the package has no OpenTelemetry
+// dependency, e.g. to build an OpenTelemetry resource:
+//
+//	attrs, err := semconv.Attributes(ctx)
+//	if err != nil {
+//		// handle error
+//	}
+//
+//	kvs := make([]attribute.KeyValue, 0, len(attrs))
+//	for _, attr := range attrs {
+//		kvs = append(kvs, attribute.String(attr.Key, attr.Value))
+//	}
+//
+//	res := resource.NewWithAttributes(otelsemconv.SchemaURL, kvs...)
+package semconv
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+// The semantic convention attribute keys.
+const (
+	CloudProvider         = "cloud.provider"
+	CloudPlatform         = "cloud.platform"
+	CloudAvailabilityZone = "cloud.availability_zone"
+	ECSClusterARN         = "aws.ecs.cluster.arn"
+	ECSTaskARN            = "aws.ecs.task.arn"
+	ECSTaskID             = "aws.ecs.task.id"
+	ECSTaskFamily         = "aws.ecs.task.family"
+	ECSTaskRevision       = "aws.ecs.task.revision"
+	ECSLaunchType         = "aws.ecs.launchtype"
+	ECSContainerARN       = "aws.ecs.container.arn"
+	ContainerID           = "container.id"
+	ContainerName         = "container.name"
+)
+
+// The semantic convention values of the cloud provider and platform.
+const (
+	CloudProviderAWS    = "aws"
+	CloudPlatformAWSECS = "aws_ecs"
+)
+
+// Attribute is a semantic convention resource attribute.
+type Attribute struct {
+	Key   string
+	Value string
+}
+
+// Attributes returns the resource attributes of the ECS task and container. The
+// metadata is fetched once and cached, see maxprocs.TaskMetadata.
+func Attributes(ctx context.Context, opts ...config.Option) ([]Attribute, error) {
+	id, err := maxprocs.TaskMetadata(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ECS task metadata: %w", err)
+	}
+
+	return FromIdentity(id), nil
+}
+
+// Map is like Attributes but returns the attributes keyed by attribute key.
+func Map(ctx context.Context, opts ...config.Option) (map[string]string, error) {
+	attrs, err := Attributes(ctx, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	m := make(map[string]string, len(attrs))
+	for _, attr := range attrs {
+		m[attr.Key] = attr.Value
+	}
+
+	return m, nil
+}
+
+// FromIdentity returns the resource attributes of the identity. Attributes with an
+// unknown value are omitted. The launch type is lowercased as per the semantic conventions.
+func FromIdentity(id maxprocs.Identity) []Attribute {
+	attrs := []Attribute{
+		{Key: CloudProvider, Value: CloudProviderAWS},
+		{Key: CloudPlatform, Value: CloudPlatformAWSECS},
+	}
+
+	for _, attr := range []Attribute{
+		{Key: CloudAvailabilityZone, Value: id.AvailabilityZone},
+		{Key: ECSClusterARN, Value: id.ClusterARN()},
+		{Key: ECSTaskARN, Value: id.TaskARN},
+		{Key: ECSTaskID, Value: id.TaskID()},
+		{Key: ECSTaskFamily, Value: id.Family},
+		{Key: ECSTaskRevision, Value: id.Revision},
+		{Key: ECSLaunchType, Value: strings.ToLower(id.LaunchType)},
+		{Key: ECSContainerARN, Value: id.ContainerARN},
+		{Key: ContainerID, Value: id.ContainerID},
+		{Key: ContainerName, Value: id.ContainerName},
+	} {
+		if attr.Value != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+
+	return attrs
+}
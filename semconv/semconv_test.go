@@ -0,0 +1,112 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package semconv_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/rdforte/gomaxecs/internal/task/tasktest"
+	"github.com/rdforte/gomaxecs/maxprocs"
+	"github.com/rdforte/gomaxecs/semconv"
+)
+
+func TestFromIdentity(t *testing.T) {
+	t.Parallel()
+
+	tableTest := []struct {
+		name     string
+		identity maxprocs.Identity
+		want     []semconv.Attribute
+	}{
+		{
+			name: "should get attributes of task and container",
+			identity: maxprocs.Identity{
+				Cluster:          "default",
+				TaskARN:          "arn:aws:ecs:us-east-1:123456789012:task/default/abc",
+				Family:           "service",
+				Revision:         "7",
+				LaunchType:       "FARGATE",
+				AvailabilityZone: "us-east-1a",
+				ContainerID:      "container-id",
+				ContainerName:    "app",
+				ContainerARN:     "arn:aws:ecs:us-east-1:123456789012:container/default/abc/def",
+			},
+			want: []semconv.Attribute{
+				{Key: semconv.CloudProvider, Value: "aws"},
+				{Key: semconv.CloudPlatform, Value: "aws_ecs"},
+				{Key: semconv.CloudAvailabilityZone, Value: "us-east-1a"},
+				{Key: semconv.ECSClusterARN, Value: "arn:aws:ecs:us-east-1:123456789012:cluster/default"},
+				{Key: semconv.ECSTaskARN, Value: "arn:aws:ecs:us-east-1:123456789012:task/default/abc"},
+				{Key: semconv.ECSTaskID, Value: "abc"},
+				{Key: semconv.ECSTaskFamily, Value: "service"},
+				{Key: semconv.ECSTaskRevision, Value: "7"},
+				{Key: semconv.ECSLaunchType, Value: "fargate"},
+				{Key: semconv.ECSContainerARN, Value: "arn:aws:ecs:us-east-1:123456789012:container/default/abc/def"},
+				{Key: semconv.ContainerID, Value: "container-id"},
+				{Key: semconv.ContainerName, Value: "app"},
+			},
+		},
+		{
+			name:     "should omit unknown attributes",
+			identity: maxprocs.Identity{Family: "service", ContainerName: "app"},
+			want: []semconv.Attribute{
+				{Key: semconv.CloudProvider, Value: "aws"},
+				{Key: semconv.CloudPlatform, Value: "aws_ecs"},
+				{Key: semconv.ECSTaskFamily, Value: "service"},
+				{Key: semconv.ContainerName, Value: "app"},
+			},
+		},
+	}
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tt.want, semconv.FromIdentity(tt.identity))
+		})
+	}
+}
+
+func TestMap(t *testing.T) {
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(`{"DockerId":"container-id","Name":"app","Limits":{"CPU":1024}}`).
+		WithTaskMetaEndpointJSON(`{"Family":"service","LaunchType":"EC2","Containers":[]}`).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	got, err := semconv.Map(context.Background())
+	require.NoError(t, err)
+
+	want := map[string]string{
+		semconv.CloudProvider: "aws",
+		semconv.CloudPlatform: "aws_ecs",
+		semconv.ECSTaskFamily: "service",
+		semconv.ECSLaunchType: "ec2",
+		semconv.ContainerID:   "container-id",
+		semconv.ContainerName: "app",
+	}
+	assert.Equal(t, want, got)
+}
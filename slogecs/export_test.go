@@ -0,0 +1,44 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package slogecs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+// SetTaskMetadata replaces the identity fetch and the retry interval until the test ends.
+func SetTaskMetadata(t *testing.T, interval time.Duration,
+	fetch func(ctx context.Context, opts ...config.Option) (maxprocs.Identity, error),
+) {
+	t.Helper()
+
+	prevInterval, prevFetch := retryInterval, taskMetadata
+	retryInterval, taskMetadata = interval, fetch
+
+	t.Cleanup(func() {
+		retryInterval, taskMetadata = prevInterval, prevFetch
+	})
+}
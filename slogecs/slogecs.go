@@ -0,0 +1,236 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+// Package slogecs provides a slog.Handler which adds the identity of the ECS task and
+// container to every record, from the same metadata used to set GOMAXPROCS.
+//
+//	logger := slog.New(slogecs.NewHandler(slog.NewJSONHandler(os.Stdout, nil)))
+//
+// The attributes are added under the ecs group:
+//
+//	{"msg":"hello","ecs":{"cluster":"default","task_arn":"arn:aws:ecs:...","task_id":"abc",
+//	"task_definition":"service:7","container_name":"app","availability_zone":"us-east-1a"}}
+package slogecs
+
+import (
+	"context"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+	"github.com/rdforte/gomaxecs/maxprocs"
+)
+
+// The group and keys of the attributes added to records.
+const (
+	Group               = "ecs"
+	KeyCluster          = "cluster"
+	KeyTaskARN          = "task_arn"
+	KeyTaskID           = "task_id"
+	KeyTaskDefinition   = "task_definition"
+	KeyContainerName    = "container_name"
+	KeyAvailabilityZone = "availability_zone"
+)
+
+//nolint:gochecknoglobals // replaced in tests.
+var (
+	// retryInterval is the min time between fetching the identity after a failure.
+	retryInterval = 30 * time.Second
+	// taskMetadata fetches the identity of the ECS task and container.
+	taskMetadata = maxprocs.TaskMetadata
+)
+
+// Handler is a slog.Handler which adds the identity of the ECS task and container
+// to every record before passing it on to the wrapped handler.
+type Handler struct {
+	next     slog.Handler
+	ops      []func(slog.Handler) slog.Handler
+	identity *identity
+
+	once    sync.Once
+	handler slog.Handler
+
+	plainOnce sync.Once
+	plain     slog.Handler
+}
+
+// NewHandler returns a Handler wrapping next. The identity is fetched from the ECS
+// metadata when the Handler is created, using opts for the metadata requests, and
+// cached for the lifetime of the process, see maxprocs.TaskMetadata. If the identity
+// can not be fetched, records are passed on as is and the identity is fetched again in
+// the background on a later record, at most every 30 seconds, so Handle never blocks
+// on the metadata requests. When not running on ECS, records are always passed on as is.
+func NewHandler(next slog.Handler, opts ...config.Option) *Handler {
+	id := &identity{opts: opts}
+	id.fetch()
+
+	return &Handler{next: next, identity: id}
+}
+
+// Enabled reports whether the wrapped handler handles records at the given level.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.next.Enabled(ctx, level)
+}
+
+// Handle adds the identity of the ECS task and container to the record and passes
+// it on to the wrapped handler.
+//
+//nolint:gocritic // slog.Record is passed by value as per slog.Handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	attrs, ok := h.identity.get()
+	if !ok {
+		h.plainOnce.Do(func() {
+			h.plain = h.build(nil)
+		})
+
+		return h.plain.Handle(ctx, r) //nolint:wrapcheck // return error of wrapped handler.
+	}
+
+	h.once.Do(func() {
+		h.handler = h.build(attrs)
+	})
+
+	return h.handler.Handle(ctx, r) //nolint:wrapcheck // return error of wrapped handler.
+}
+
+// build returns the wrapped handler with the identity attributes, if any, followed by
+// the attributes and groups of the Handler.
+func (h *Handler) build(attrs []slog.Attr) slog.Handler {
+	handler := h.next
+
+	if len(attrs) > 0 {
+		handler = handler.WithAttrs([]slog.Attr{{Key: Group, Value: slog.GroupValue(attrs...)}})
+	}
+
+	for _, op := range h.ops {
+		handler = op(handler)
+	}
+
+	return handler
+}
+
+// WithAttrs returns a Handler whose records include attrs. The identity is added
+// ahead of attrs, so is never nested under a group.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler {
+		return next.WithAttrs(attrs)
+	})
+}
+
+// WithGroup returns a Handler which nests the attributes of records under name.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler {
+		return next.WithGroup(name)
+	})
+}
+
+// with returns a copy of the Handler with op applied to the wrapped handler once the
+// identity is added. The identity is shared with the copy so is only fetched once.
+func (h *Handler) with(op func(slog.Handler) slog.Handler) *Handler {
+	return &Handler{
+		next:     h.next,
+		ops:      append(slices.Clip(h.ops), op),
+		identity: h.identity,
+	}
+}
+
+// identity holds the identity attributes shared by a Handler and its copies. Only a
+// successful fetch is cached.
+type identity struct {
+	opts []config.Option
+
+	mu       sync.Mutex
+	attrs    []slog.Attr
+	ok       bool
+	fetching bool
+	retryAt  time.Time
+}
+
+// get returns the identity attributes and true once fetched. Otherwise a failed fetch
+// is retried in the background, once retryInterval has passed since the failure.
+func (id *identity) get() ([]slog.Attr, bool) {
+	id.mu.Lock()
+	defer id.mu.Unlock()
+
+	if id.ok || id.fetching || time.Now().Before(id.retryAt) {
+		return id.attrs, id.ok
+	}
+
+	id.fetching = true
+	go id.fetch()
+
+	return nil, false
+}
+
+// fetch fetches the identity attributes, caching them on success.
+func (id *identity) fetch() {
+	attrs, err := identityAttrs(id.opts...)
+
+	id.mu.Lock()
+	defer id.mu.Unlock()
+
+	id.fetching = false
+
+	if err != nil {
+		id.retryAt = time.Now().Add(retryInterval)
+		return
+	}
+
+	id.attrs, id.ok = attrs, true
+}
+
+// identityAttrs returns the attributes of the ECS task and container identity,
+// omitting any which are not known, or nil when not running on ECS.
+func identityAttrs(opts ...config.Option) ([]slog.Attr, error) {
+	if !maxprocs.IsECS() {
+		return nil, nil //nolint:nilnil // there is no identity when not running on ECS.
+	}
+
+	id, err := taskMetadata(context.Background(), opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	taskDefinition := id.Family
+	if id.Family != "" && id.Revision != "" {
+		taskDefinition += ":" + id.Revision
+	}
+
+	all := []slog.Attr{
+		slog.String(KeyCluster, id.Cluster),
+		slog.String(KeyTaskARN, id.TaskARN),
+		slog.String(KeyTaskID, id.TaskID()),
+		slog.String(KeyTaskDefinition, taskDefinition),
+		slog.String(KeyContainerName, id.ContainerName),
+		slog.String(KeyAvailabilityZone, id.AvailabilityZone),
+	}
+
+	attrs := make([]slog.Attr, 0, len(all))
+
+	for _, attr := range all {
+		if attr.Value.String() != "" {
+			attrs = append(attrs, attr)
+		}
+	}
+
+	return attrs, nil
+}
@@ -0,0 +1,154 @@
+// Copyright 2004 Ryan Forte
+//
+// Permission is hereby granted, free of charge, to any person obtaining a copy
+// of this software and associated documentation files (the "Software"), to deal
+// in the Software without restriction, including without limitation the rights
+// to use, copy, modify, merge, publish, distribute, sublicense, and/or sell
+// copies of the Software, and to permit persons to whom the Software is
+// furnished to do so, subject to the following conditions:
+//
+// The above copyright notice and this permission notice shall be included in
+// all copies or substantial portions of the Software.
+//
+// THE SOFTWARE IS PROVIDED "AS IS", WITHOUT WARRANTY OF ANY KIND, EXPRESS OR
+// IMPLIED, INCLUDING BUT NOT LIMITED TO THE WARRANTIES OF MERCHANTABILITY,
+// FITNESS FOR A PARTICULAR PURPOSE AND NONINFRINGEMENT. IN NO EVENT SHALL THE
+// AUTHORS OR COPYRIGHT HOLDERS BE LIABLE FOR ANY CLAIM, DAMAGES OR OTHER
+// LIABILITY, WHETHER IN AN ACTION OF CONTRACT, TORT OR OTHERWISE, ARISING FROM,
+// OUT OF OR IN CONNECTION WITH THE SOFTWARE OR THE USE OR OTHER DEALINGS IN
+// THE SOFTWARE.
+
+package slogecs_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/rdforte/gomaxecs/internal/config"
+	"github.com/rdforte/gomaxecs/internal/task/tasktest"
+	"github.com/rdforte/gomaxecs/maxprocs"
+	"github.com/rdforte/gomaxecs/slogecs"
+)
+
+const (
+	metaURIEnv    = "ECS_CONTAINER_METADATA_URI_V4"
+	containerMeta = `{"DockerId":"container-id","Name":"app","Limits":{"CPU":1024}}`
+	taskMeta      = `{"Cluster":"default","TaskARN":"arn:aws:ecs:us-east-1:123456789012:task/default/abc",` +
+		`"Family":"service","Revision":"7","AvailabilityZone":"us-east-1a","Containers":[]}`
+	ecsAttrs = `"ecs":{"cluster":"default","task_arn":"arn:aws:ecs:us-east-1:123456789012:task/default/abc",` +
+		`"task_id":"abc","task_definition":"service:7","container_name":"app","availability_zone":"us-east-1a"}`
+)
+
+func TestHandler_AddsIdentity(t *testing.T) {
+	tableTest := []struct {
+		name    string
+		log     func(logger *slog.Logger)
+		wantLog string
+	}{
+		{
+			name: "should add identity to record",
+			log: func(logger *slog.Logger) {
+				logger.Info("hello", "key", "value")
+			},
+			wantLog: `{"level":"INFO","msg":"hello",` + ecsAttrs + `,"key":"value"}`,
+		},
+		{
+			name: "should add identity ahead of attributes",
+			log: func(logger *slog.Logger) {
+				logger.With("key", "value").Info("hello")
+			},
+			wantLog: `{"level":"INFO","msg":"hello",` + ecsAttrs + `,"key":"value"}`,
+		},
+		{
+			name: "should not nest identity under group",
+			log: func(logger *slog.Logger) {
+				logger.WithGroup("request").With("id", 1).Info("hello", "path", "/")
+			},
+			wantLog: `{"level":"INFO","msg":"hello",` + ecsAttrs + `,"request":{"id":1,"path":"/"}}`,
+		},
+	}
+
+	agent := tasktest.NewECSAgent(t).
+		WithContainerMetaEndpointJSON(containerMeta).
+		WithTaskMetaEndpointJSON(taskMeta).
+		Start().
+		SetMetaURIEnv()
+	defer agent.Close()
+
+	for _, tt := range tableTest {
+		t.Run(tt.name, func(t *testing.T) {
+			buf := new(bytes.Buffer)
+			tt.log(slog.New(slogecs.NewHandler(newJSONHandler(buf))))
+
+			assert.JSONEq(t, tt.wantLog, buf.String())
+		})
+	}
+}
+
+func TestHandler_RetriesFailedFetch(t *testing.T) {
+	t.Setenv(metaURIEnv, "http://169.254.170.2/v4")
+
+	var calls atomic.Int32
+
+	slogecs.SetTaskMetadata(t, time.Millisecond, func(context.Context, ...config.Option) (maxprocs.Identity, error) {
+		if calls.Add(1) == 1 {
+			return maxprocs.Identity{}, errors.New("metadata unavailable")
+		}
+
+		return maxprocs.Identity{Cluster: "default", ContainerName: "app"}, nil
+	})
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(slogecs.NewHandler(newJSONHandler(buf)))
+
+	logger.Info("hello")
+	assert.JSONEq(t, `{"level":"INFO","msg":"hello"}`, buf.String())
+
+	assert.Eventually(t, func() bool {
+		buf.Reset()
+		logger.Info("hello")
+
+		return buf.String() != `{"level":"INFO","msg":"hello"}`+"\n"
+	}, time.Second, 5*time.Millisecond)
+
+	assert.JSONEq(t, `{"level":"INFO","msg":"hello","ecs":{"cluster":"default","container_name":"app"}}`, buf.String())
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+func TestHandler_PassesRecordsWhenNotECS(t *testing.T) {
+	t.Setenv(metaURIEnv, "")
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(slogecs.NewHandler(newJSONHandler(buf)))
+	logger.Info("hello", "key", "value")
+
+	assert.JSONEq(t, `{"level":"INFO","msg":"hello","key":"value"}`, buf.String())
+}
+
+func TestHandler_Enabled(t *testing.T) {
+	buf := new(bytes.Buffer)
+	logger := slog.New(slogecs.NewHandler(slog.NewJSONHandler(buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+	logger.Info("hello")
+
+	assert.Empty(t, buf.String())
+}
+
+// newJSONHandler returns a JSON handler without the time, so records can be compared.
+func newJSONHandler(buf *bytes.Buffer) slog.Handler {
+	return slog.NewJSONHandler(buf, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if len(groups) == 0 && attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+
+			return attr
+		},
+	})
+}